@@ -0,0 +1,35 @@
+package stackerr
+
+import "strings"
+
+// TestingT is the minimal interface (satisfied by *testing.T and *testing.B) used by AssertCreatedAt
+//
+// It is defined here so that the stackerr package does not need to import the testing package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertCreatedAt asserts that the first StackError in the chain of the error (see AsStackError) was created at a
+// site whose top frame function contains funcSubstr (e.g. "mypkg.(*Service).Load")
+//
+// If there is no StackError in the chain, the StackError has no stack info or the top frame function does not contain
+// funcSubstr, the test is failed (using t.Errorf). Returns whether the assertion passed
+func AssertCreatedAt(t TestingT, err error, funcSubstr string) bool {
+	t.Helper()
+	se, ok := AsStackError(err)
+	if !ok {
+		t.Errorf("expected a StackError in the chain of error: %v", err)
+		return false
+	}
+	fr, ok := se.CallerFrame()
+	if !ok {
+		t.Errorf("expected StackError to have stack info: %v", err)
+		return false
+	}
+	if !strings.Contains(fr.Function, funcSubstr) {
+		t.Errorf("expected error to be created at %q, but was created at %q (%s:%d)", funcSubstr, fr.Function, FrameFile(fr), fr.Line)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type mockTestingT struct {
+	helpers int
+	errors  []string
+}
+
+func (m *mockTestingT) Helper() {
+	m.helpers++
+}
+
+func (m *mockTestingT) Errorf(format string, args ...any) {
+	m.errors = append(m.errors, fmt.Sprintf(format, args...))
+}
+
+var _ TestingT = (*testing.T)(nil)
+
+func createdHere() error {
+	return New("fooey")
+}
+
+func TestAssertCreatedAt(t *testing.T) {
+	t.Run("passes", func(t *testing.T) {
+		mt := &mockTestingT{}
+		require.True(t, AssertCreatedAt(mt, createdHere(), "stackerr.createdHere"))
+		require.Empty(t, mt.errors)
+		require.Equal(t, 1, mt.helpers)
+	})
+	t.Run("passes wrapped", func(t *testing.T) {
+		mt := &mockTestingT{}
+		require.True(t, AssertCreatedAt(mt, fmt.Errorf("wrapped: %w", createdHere()), "createdHere"))
+		require.Empty(t, mt.errors)
+	})
+	t.Run("fails different site", func(t *testing.T) {
+		mt := &mockTestingT{}
+		require.False(t, AssertCreatedAt(mt, createdHere(), "somewhereElse"))
+		require.Len(t, mt.errors, 1)
+		require.Contains(t, mt.errors[0], `expected error to be created at "somewhereElse", but was created at "github.com/go-andiamo/stackerr.createdHere"`)
+		require.Contains(t, mt.errors[0], "assert_test.go:")
+	})
+	t.Run("fails not StackError", func(t *testing.T) {
+		mt := &mockTestingT{}
+		require.False(t, AssertCreatedAt(mt, errors.New("fooey"), "createdHere"))
+		require.Equal(t, []string{"expected a StackError in the chain of error: fooey"}, mt.errors)
+	})
+	t.Run("fails nil", func(t *testing.T) {
+		mt := &mockTestingT{}
+		require.False(t, AssertCreatedAt(mt, nil, "createdHere"))
+		require.Len(t, mt.errors, 1)
+	})
+	t.Run("fails no stack", func(t *testing.T) {
+		CaptureStack = false
+		defer func() {
+			CaptureStack = true
+		}()
+		mt := &mockTestingT{}
+		require.False(t, AssertCreatedAt(mt, createdHere(), "createdHere"))
+		require.Equal(t, []string{"expected StackError to have stack info: fooey"}, mt.errors)
+	})
+}
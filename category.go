@@ -0,0 +1,104 @@
+package stackerr
+
+import "net/http"
+
+// Category is the classification of an error (see StackError.WithCategory)
+type Category string
+
+const (
+	CategoryNotFound     Category = "not_found"
+	CategoryInvalid      Category = "invalid"
+	CategoryConflict     Category = "conflict"
+	CategoryUnauthorized Category = "unauthorized"
+	CategoryForbidden    Category = "forbidden"
+	CategoryUnavailable  Category = "unavailable"
+	CategoryInternal     Category = "internal"
+)
+
+func (e *err) WithCategory(category Category) StackError {
+	r := e.clone()
+	r.category = category
+	return r
+}
+
+func (e *err) Category() Category {
+	return e.category
+}
+
+// CategoryOf walks the Unwrap chain of the error and returns the first category found (see StackError.WithCategory)
+//
+// If no category is found, an empty Category is returned
+func CategoryOf(err error) Category {
+	result, _ := firstInChain(err, explicitCategory)
+	return result
+}
+
+func explicitCategory(e error) (Category, bool) {
+	if c, ok := e.(interface{ Category() Category }); ok && c.Category() != "" {
+		return c.Category(), true
+	}
+	return "", false
+}
+
+// CategoryHTTPStatus returns the default HTTP status for the category
+//
+// Categories without a mapping (including an empty Category) return http.StatusInternalServerError
+func CategoryHTTPStatus(category Category) int {
+	switch category {
+	case CategoryNotFound:
+		return http.StatusNotFound
+	case CategoryInvalid:
+		return http.StatusBadRequest
+	case CategoryConflict:
+		return http.StatusConflict
+	case CategoryUnauthorized:
+		return http.StatusUnauthorized
+	case CategoryForbidden:
+		return http.StatusForbidden
+	case CategoryUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// NotFound creates a new StackError with stack info and CategoryNotFound
+func NotFound(msg string) StackError {
+	return newCategoryError(msg, CategoryNotFound)
+}
+
+// Invalid creates a new StackError with stack info and CategoryInvalid
+func Invalid(msg string) StackError {
+	return newCategoryError(msg, CategoryInvalid)
+}
+
+// Conflict creates a new StackError with stack info and CategoryConflict
+func Conflict(msg string) StackError {
+	return newCategoryError(msg, CategoryConflict)
+}
+
+// Unauthorized creates a new StackError with stack info and CategoryUnauthorized
+func Unauthorized(msg string) StackError {
+	return newCategoryError(msg, CategoryUnauthorized)
+}
+
+// Forbidden creates a new StackError with stack info and CategoryForbidden
+func Forbidden(msg string) StackError {
+	return newCategoryError(msg, CategoryForbidden)
+}
+
+// Unavailable creates a new StackError with stack info and CategoryUnavailable
+func Unavailable(msg string) StackError {
+	return newCategoryError(msg, CategoryUnavailable)
+}
+
+// Internal creates a new StackError with stack info and CategoryInternal
+func Internal(msg string) StackError {
+	return newCategoryError(msg, CategoryInternal)
+}
+
+// newCategoryError is the helper for the category constructors (e.g. NotFound) - so the stack is captured with an entry depth of 2
+func newCategoryError(msg string, category Category) *err {
+	e := allocError(msg, getStackInfo(2, newOptions()), nil)
+	e.category = category
+	return e.created()
+}
@@ -0,0 +1,77 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestError_WithCategory(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, Category(""), e.Category())
+	e2 := e.WithCategory(CategoryConflict)
+	require.Equal(t, CategoryConflict, e2.Category())
+	require.Equal(t, Category(""), e.Category())
+}
+
+func TestCategoryConstructors(t *testing.T) {
+	testCases := []struct {
+		fn       func(msg string) StackError
+		expected Category
+	}{
+		{fn: NotFound, expected: CategoryNotFound},
+		{fn: Invalid, expected: CategoryInvalid},
+		{fn: Conflict, expected: CategoryConflict},
+		{fn: Unauthorized, expected: CategoryUnauthorized},
+		{fn: Forbidden, expected: CategoryForbidden},
+		{fn: Unavailable, expected: CategoryUnavailable},
+		{fn: Internal, expected: CategoryInternal},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("[%d]%s", i+1, tc.expected), func(t *testing.T) {
+			e := tc.fn("fooey")
+			require.Equal(t, "fooey", e.Error())
+			require.Equal(t, tc.expected, e.Category())
+			require.NotEmpty(t, e.StackInfo())
+			require.Equal(t, "github.com/go-andiamo/stackerr.TestCategoryConstructors.func1", e.StackInfo()[0].Function)
+		})
+	}
+}
+
+func TestCategoryOf(t *testing.T) {
+	require.Equal(t, Category(""), CategoryOf(nil))
+	require.Equal(t, Category(""), CategoryOf(errors.New("fooey")))
+	require.Equal(t, Category(""), CategoryOf(New("fooey")))
+	e := Wrap(Wrap(NotFound("inner"), "middle"), "outer")
+	require.Equal(t, CategoryNotFound, CategoryOf(e))
+	require.Equal(t, CategoryNotFound, CategoryOf(fmt.Errorf("plain: %w", e)))
+	require.Equal(t, CategoryInternal, CategoryOf(Wrap(e, "outermost").WithCategory(CategoryInternal)))
+}
+
+func TestCategoryHTTPStatus(t *testing.T) {
+	require.Equal(t, http.StatusNotFound, CategoryHTTPStatus(CategoryNotFound))
+	require.Equal(t, http.StatusBadRequest, CategoryHTTPStatus(CategoryInvalid))
+	require.Equal(t, http.StatusConflict, CategoryHTTPStatus(CategoryConflict))
+	require.Equal(t, http.StatusUnauthorized, CategoryHTTPStatus(CategoryUnauthorized))
+	require.Equal(t, http.StatusForbidden, CategoryHTTPStatus(CategoryForbidden))
+	require.Equal(t, http.StatusServiceUnavailable, CategoryHTTPStatus(CategoryUnavailable))
+	require.Equal(t, http.StatusInternalServerError, CategoryHTTPStatus(CategoryInternal))
+	require.Equal(t, http.StatusInternalServerError, CategoryHTTPStatus(""))
+	require.Equal(t, http.StatusInternalServerError, CategoryHTTPStatus("unknown"))
+}
+
+func TestError_FormatCategory(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := NotFound("fooey")
+	require.Equal(t, "fooey\nCategory: not_found", fmt.Sprintf("%+v", e))
+	require.Equal(t, "fooey", fmt.Sprintf("%v", e))
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey","category":"not_found"}`, string(data))
+}
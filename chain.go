@@ -0,0 +1,96 @@
+package stackerr
+
+import (
+	"errors"
+	"reflect"
+)
+
+// maxChainLength is the maximum number of errors traversed when walking an Unwrap chain
+// (guarding against self-referencing chains)
+const maxChainLength = 1000
+
+// Causes returns the error and each error in its Unwrap chain - in order from outermost to innermost
+func Causes(err error) []error {
+	var result []error
+	for ; err != nil && len(result) < maxChainLength; err = errors.Unwrap(err) {
+		result = append(result, err)
+	}
+	return result
+}
+
+// RootCause returns the innermost error in the Unwrap chain of the error
+//
+// If the error has no cause, the error itself is returned
+func RootCause(err error) error {
+	for i := 0; err != nil && i < maxChainLength; i++ {
+		next := errors.Unwrap(err)
+		if next == nil {
+			break
+		}
+		err = next
+	}
+	return err
+}
+
+// firstInChain walks the Unwrap chain of the error returning the first value found by the get func
+func firstInChain[T any](err error, get func(err error) (T, bool)) (result T, ok bool) {
+	for i := 0; err != nil && i < maxChainLength; i++ {
+		if result, ok = get(err); ok {
+			return result, true
+		}
+		err = errors.Unwrap(err)
+	}
+	return result, false
+}
+
+// AsStackError returns the first StackError found in the chain of the error (using errors.As)
+//
+// This saves callers from the errors.As boilerplate, e.g. when a StackError has been wrapped using fmt.Errorf with %w
+func AsStackError(err error) (StackError, bool) {
+	var result StackError
+	if errors.As(err, &result) {
+		return result, true
+	}
+	return nil, false
+}
+
+// Walk performs a depth-first traversal of the entire error tree - calling the fn for each error (starting with the error itself)
+//
+// Both single cause (Unwrap() error) and multiple cause (Unwrap() []error, e.g. errors.Join, and StackError.Unwraps) errors are traversed.
+// Each error is visited at most once (guarding against cycles) and the traversal stops early if the fn returns false
+func Walk(err error, fn func(error) bool) {
+	visited := make(map[error]struct{})
+	walk(err, fn, visited)
+}
+
+func walk(err error, fn func(error) bool, visited map[error]struct{}) bool {
+	if err == nil {
+		return true
+	}
+	if reflect.TypeOf(err).Comparable() {
+		if _, ok := visited[err]; ok {
+			return true
+		}
+		visited[err] = struct{}{}
+	}
+	if !fn(err) {
+		return false
+	}
+	switch u := err.(type) {
+	case interface{ Unwraps() []error }:
+		for _, c := range u.Unwraps() {
+			if !walk(c, fn, visited) {
+				return false
+			}
+		}
+	case interface{ Unwrap() []error }:
+		for _, c := range u.Unwrap() {
+			if !walk(c, fn, visited) {
+				return false
+			}
+		}
+	case interface{ Unwrap() error }:
+		return walk(u.Unwrap(), fn, visited)
+	}
+	return true
+}
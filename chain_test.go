@@ -0,0 +1,129 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCauses(t *testing.T) {
+	root := errors.New("root")
+	middle := Wrap(root, "middle")
+	outer := fmt.Errorf("outer: %w", middle)
+	causes := Causes(outer)
+	require.Len(t, causes, 3)
+	require.Equal(t, outer, causes[0])
+	require.Equal(t, middle, causes[1])
+	require.Equal(t, root, causes[2])
+
+	require.Equal(t, []error{root}, Causes(root))
+	require.Nil(t, Causes(nil))
+}
+
+func TestRootCause(t *testing.T) {
+	root := errors.New("root")
+	e := Wrap(Wrap(Wrap(root, "inner"), "middle"), "outer")
+	require.Equal(t, root, RootCause(e))
+	require.Equal(t, root, e.RootCause())
+
+	e = New("fooey")
+	require.Equal(t, e, RootCause(e))
+	require.Equal(t, e, e.RootCause())
+	require.Nil(t, RootCause(nil))
+}
+
+func TestCauses_Cyclic(t *testing.T) {
+	c := &cyclicError{}
+	c.cause = c
+	require.Len(t, Causes(c), maxChainLength)
+	require.Equal(t, c, RootCause(c))
+	_, ok := CodeOf(c)
+	require.False(t, ok)
+}
+
+type cyclicError struct {
+	cause error
+}
+
+func (c *cyclicError) Error() string {
+	return "cyclic"
+}
+
+func (c *cyclicError) Unwrap() error {
+	return c.cause
+}
+
+func TestAsStackError(t *testing.T) {
+	_, ok := AsStackError(nil)
+	require.False(t, ok)
+	_, ok = AsStackError(errors.New("fooey"))
+	require.False(t, ok)
+
+	inner := New("inner")
+	outer := Wrap(inner, "outer")
+	se, ok := AsStackError(outer)
+	require.True(t, ok)
+	require.Equal(t, outer, se)
+
+	se, ok = AsStackError(fmt.Errorf("plain: %w", inner))
+	require.True(t, ok)
+	require.Equal(t, inner, se)
+	require.NotEmpty(t, se.StackInfo())
+
+	se, ok = AsStackError(fmt.Errorf("plain: %w", fmt.Errorf("plainer: %w", outer)))
+	require.True(t, ok)
+	require.Equal(t, outer, se)
+
+	se, ok = AsStackError(errors.Join(errors.New("other"), inner))
+	require.True(t, ok)
+	require.Equal(t, inner, se)
+}
+
+func TestWalk(t *testing.T) {
+	root1 := errors.New("root1")
+	root2 := errors.New("root2")
+	plain := fmt.Errorf("plain: %w", root2)
+	joined := Join(Wrap(root1, "wrapped"), plain)
+	top := Wrap(joined, "top")
+
+	collected := make([]string, 0)
+	Walk(top, func(err error) bool {
+		collected = append(collected, err.Error())
+		return true
+	})
+	require.Equal(t, []string{"top", joined.Error(), "wrapped", "root1", "plain: root2", "root2"}, collected)
+
+	t.Run("stops early", func(t *testing.T) {
+		collected := make([]string, 0)
+		Walk(top, func(err error) bool {
+			collected = append(collected, err.Error())
+			return err != root1
+		})
+		require.Equal(t, []string{"top", joined.Error(), "wrapped", "root1"}, collected)
+	})
+	t.Run("std join", func(t *testing.T) {
+		count := 0
+		Walk(errors.Join(root1, errors.Join(root2, root1)), func(err error) bool {
+			count++
+			return true
+		})
+		require.Equal(t, 4, count)
+	})
+	t.Run("cycle", func(t *testing.T) {
+		c := &cyclicError{}
+		c.cause = &cyclicError{cause: c}
+		count := 0
+		Walk(c, func(err error) bool {
+			count++
+			return true
+		})
+		require.Equal(t, 2, count)
+	})
+	t.Run("nil", func(t *testing.T) {
+		Walk(nil, func(err error) bool {
+			require.Fail(t, "should not be called")
+			return true
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package stackerr
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiCyan   = "\x1b[36m"
+	ansiYellow = "\x1b[33m"
+)
+
+// ColorFrameFormatter is a FrameFormatter that formats each frame with the function name and file:line wrapped in
+// ANSI color codes (for terminal debugging)
+//
+// e.g. "\n\tpkg.Func file.go:10" (with the function name in cyan and the file:line in yellow)
+//
+// If NoColor is set, no color codes are output. Use NewColorFrameFormatter to determine NoColor automatically
+type ColorFrameFormatter struct {
+	NoColor bool
+}
+
+var _ FrameFormatter = (*ColorFrameFormatter)(nil)
+
+// NewColorFrameFormatter creates a new ColorFrameFormatter - with NoColor set if the NO_COLOR environment variable is
+// set (to any non-empty value) or os.Stderr is not a terminal (see IsTerminal)
+//
+// Note: detection is best-effort - the FrameFormatter does not know the writer to which it is ultimately output
+func NewColorFrameFormatter() *ColorFrameFormatter {
+	return &ColorFrameFormatter{
+		NoColor: os.Getenv("NO_COLOR") != "" || !IsTerminal(os.Stderr),
+	}
+}
+
+func (cf *ColorFrameFormatter) StartLine() string {
+	return "\n" + cf.color(ansiBold, "Stack:")
+}
+
+func (cf *ColorFrameFormatter) FrameLine(frame runtime.Frame) string {
+	return "\n\t" + cf.color(ansiCyan, frame.Function) + " " + cf.color(ansiYellow, fmt.Sprintf("%s:%d", FrameFile(frame), frame.Line))
+}
+
+func (cf *ColorFrameFormatter) color(code string, s string) string {
+	if cf.NoColor {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// IsTerminal determines (best-effort) whether the file is a terminal (i.e. a character device)
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
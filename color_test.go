@@ -0,0 +1,50 @@
+package stackerr
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestColorFrameFormatter(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey")
+	fr, _ := e.CallerFrame()
+
+	cf := &ColorFrameFormatter{}
+	require.Equal(t, "\n\x1b[1mStack:\x1b[0m", cf.StartLine())
+	require.Equal(t, fmt.Sprintf("\n\t\x1b[36m%s\x1b[0m \x1b[33m%s:%d\x1b[0m", fr.Function, fr.File, fr.Line), cf.FrameLine(fr))
+
+	cf.NoColor = true
+	require.Equal(t, "\nStack:", cf.StartLine())
+	require.Equal(t, fmt.Sprintf("\n\t%s %s:%d", fr.Function, fr.File, fr.Line), cf.FrameLine(fr))
+	require.False(t, strings.Contains(cf.FrameLine(fr), "\x1b["))
+
+	DefaultFrameFormatter = &ColorFrameFormatter{}
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.True(t, strings.HasPrefix(fmt.Sprintf("%+v", e), "fooey\n\x1b[1mStack:\x1b[0m\n\t\x1b[36m"))
+}
+
+func TestNewColorFrameFormatter(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	require.True(t, NewColorFrameFormatter().NoColor)
+	t.Setenv("NO_COLOR", "")
+	require.Equal(t, !IsTerminal(os.Stderr), NewColorFrameFormatter().NoColor)
+}
+
+func TestIsTerminal(t *testing.T) {
+	require.False(t, IsTerminal(nil))
+	f, err := os.CreateTemp(t.TempDir(), "tty")
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	require.False(t, IsTerminal(f))
+}
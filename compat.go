@@ -0,0 +1,43 @@
+package stackerr
+
+import "reflect"
+
+// WrapCompat wraps an existing error with a StackError - as with Wrap, except that if the wrapped error (or an error in its
+// Unwrap chain) carries a github.com/pkg/errors compatible stack trace (i.e. has a StackTrace() method returning a slice of
+// uintptr based frames), the stack info is converted from that stack trace (rather than being captured at the point of wrapping)
+//
+// This avoids a dependency on github.com/pkg/errors - the StackTrace method is detected using reflection
+func WrapCompat(err error, msg string) StackError {
+	if err == nil {
+		return nil
+	} else if pcs, ok := firstInChain(err, compatStackTrace); ok {
+		if !CaptureStackValue() {
+			return newError(msg, callStack{frames: StackInfo{}}, err)
+		}
+		return newError(msg, callStack{frames: resolveFrames(pcs, newOptions())}, err)
+	}
+	return newError(msg, getStackInfo(1, newOptions()), err)
+}
+
+// compatStackTrace returns the program counters of a github.com/pkg/errors compatible stack trace
+//
+// Note: a pkg/errors Frame, interpreted as a uintptr, is the program counter + 1 - i.e. the return address as
+// returned by runtime.Callers (and expected by runtime.CallersFrames)
+func compatStackTrace(err error) ([]uintptr, bool) {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil, false
+	}
+	if rt := m.Type().Out(0); rt.Kind() != reflect.Slice || rt.Elem().Kind() != reflect.Uintptr {
+		return nil, false
+	}
+	st := m.Call(nil)[0]
+	if st.Len() == 0 {
+		return nil, false
+	}
+	pcs := make([]uintptr, st.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(st.Index(i).Uint())
+	}
+	return pcs, true
+}
@@ -0,0 +1,79 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"testing"
+)
+
+// fakeFrame mimics github.com/pkg/errors Frame
+type fakeFrame uintptr
+
+// fakeStackTrace mimics github.com/pkg/errors StackTrace
+type fakeStackTrace []fakeFrame
+
+type fakePkgError struct {
+	stack []uintptr
+}
+
+func newFakePkgError() *fakePkgError {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	return &fakePkgError{stack: pcs[:n]}
+}
+
+func (e *fakePkgError) Error() string {
+	return "fake"
+}
+
+func (e *fakePkgError) StackTrace() fakeStackTrace {
+	result := make(fakeStackTrace, 0, len(e.stack))
+	for _, pc := range e.stack {
+		result = append(result, fakeFrame(pc))
+	}
+	return result
+}
+
+func fakeOrigin() *fakePkgError {
+	return newFakePkgError()
+}
+
+func TestWrapCompat(t *testing.T) {
+	require.Nil(t, WrapCompat(nil, "fooey"))
+
+	t.Run("pkg errors stack", func(t *testing.T) {
+		cause := fakeOrigin()
+		e := WrapCompat(cause, "fooey")
+		require.Equal(t, "fooey", e.Error())
+		require.ErrorIs(t, e, cause)
+		si := e.StackInfo()
+		require.NotEmpty(t, si)
+		require.Equal(t, "github.com/go-andiamo/stackerr.fakeOrigin", si[0].Function)
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestWrapCompat.func1", si[1].Function)
+	})
+	t.Run("wrapped pkg errors stack", func(t *testing.T) {
+		e := WrapCompat(fmt.Errorf("plain: %w", fakeOrigin()), "fooey")
+		require.Equal(t, "github.com/go-andiamo/stackerr.fakeOrigin", e.StackInfo()[0].Function)
+	})
+	t.Run("plain", func(t *testing.T) {
+		e := WrapCompat(errors.New("plain"), "fooey")
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestWrapCompat.func3", e.StackInfo()[0].Function)
+	})
+	t.Run("stack error", func(t *testing.T) {
+		e := WrapCompat(New("inner"), "fooey")
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestWrapCompat.func4", e.StackInfo()[0].Function)
+	})
+	t.Run("empty pkg errors stack", func(t *testing.T) {
+		e := WrapCompat(&fakePkgError{}, "fooey")
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestWrapCompat.func5", e.StackInfo()[0].Function)
+	})
+	t.Run("no capture", func(t *testing.T) {
+		CaptureStack = false
+		defer func() {
+			CaptureStack = true
+		}()
+		require.Empty(t, WrapCompat(fakeOrigin(), "fooey").StackInfo())
+	})
+}
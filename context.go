@@ -0,0 +1,33 @@
+package stackerr
+
+import "context"
+
+type contextKey struct{}
+
+// ContextWithError returns a copy of the context with the StackError stored in it (see ErrorFromContext)
+//
+// If err is nil, the context is returned unchanged
+func ContextWithError(ctx context.Context, err StackError) context.Context {
+	if err == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, err)
+}
+
+// ErrorFromContext returns the StackError stored in the context (see ContextWithError)
+func ErrorFromContext(ctx context.Context) (StackError, bool) {
+	err, ok := ctx.Value(contextKey{}).(StackError)
+	return err, ok
+}
+
+// FromContext returns a StackError (with stack info) wrapping the error of the context (i.e. ctx.Err()) with the message - or
+// nil if the context has no error
+//
+// This standardizes the common pattern of checking ctx.Err() and wrapping it. The returned error matches
+// context.Canceled or context.DeadlineExceeded (using errors.Is) via its cause
+func FromContext(ctx context.Context, msg string) StackError {
+	if cerr := ctx.Err(); cerr != nil {
+		return newError(msg, getStackInfo(1, newOptions()), cerr)
+	}
+	return nil
+}
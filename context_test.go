@@ -0,0 +1,62 @@
+package stackerr
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestContextWithError(t *testing.T) {
+	e := New("fooey")
+	ctx := ContextWithError(context.Background(), e)
+	e2, ok := ErrorFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, e, e2)
+
+	ctx = context.WithValue(ctx, testContextKey{}, "value")
+	e2, ok = ErrorFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, e, e2)
+}
+
+func TestContextWithError_Nil(t *testing.T) {
+	ctx := context.Background()
+	require.Equal(t, ctx, ContextWithError(ctx, nil))
+	_, ok := ErrorFromContext(ContextWithError(ctx, nil))
+	require.False(t, ok)
+}
+
+func TestErrorFromContext_Missing(t *testing.T) {
+	e, ok := ErrorFromContext(context.Background())
+	require.False(t, ok)
+	require.Nil(t, e)
+}
+
+type testContextKey struct{}
+
+func TestFromContext(t *testing.T) {
+	t.Run("live", func(t *testing.T) {
+		require.Nil(t, FromContext(context.Background(), "fooey"))
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		e := FromContext(ctx, "fooey")
+		require.Error(t, e)
+		require.Equal(t, "fooey", e.Error())
+		require.Equal(t, "fooey: context canceled", e.FullMessage())
+		require.ErrorIs(t, e, context.Canceled)
+		require.NotErrorIs(t, e, context.DeadlineExceeded)
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestFromContext.func2", e.StackInfo()[0].Function)
+	})
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+		defer cancel()
+		e := FromContext(ctx, "fooey")
+		require.Error(t, e)
+		require.ErrorIs(t, e, context.DeadlineExceeded)
+		require.NotErrorIs(t, e, context.Canceled)
+		require.True(t, IsTimeout(e))
+	})
+}
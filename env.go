@@ -0,0 +1,60 @@
+package stackerr
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// environment variable names used by ConfigureFromEnv
+const (
+	EnvMaxDepth   = "STACKERR_MAX_DEPTH"
+	EnvPackage    = "STACKERR_PACKAGE"
+	EnvCapture    = "STACKERR_CAPTURE"
+	EnvTrimPrefix = "STACKERR_TRIM_PREFIX"
+)
+
+// ConfigureFromEnv configures settings from environment variables:
+//
+//   - STACKERR_MAX_DEPTH sets MaxStackDepth (unsigned integer)
+//   - STACKERR_PACKAGE sets DefaultPackageName
+//   - STACKERR_CAPTURE sets CaptureStack (true/false)
+//   - STACKERR_TRIM_PREFIX sets TrimFilePrefix
+//
+// Only environment variables that are set are applied - and if any value is malformed, an error is returned
+// and no settings are changed
+//
+// Note: this is never called automatically
+func ConfigureFromEnv() error {
+	maxDepth, hasMaxDepth := os.LookupEnv(EnvMaxDepth)
+	var depth uint64
+	if hasMaxDepth {
+		var err error
+		if depth, err = strconv.ParseUint(maxDepth, 10, 0); err != nil {
+			return fmt.Errorf("invalid %s value %q: %w", EnvMaxDepth, maxDepth, err)
+		}
+	}
+	captureEnv, hasCapture := os.LookupEnv(EnvCapture)
+	var capture bool
+	if hasCapture {
+		var err error
+		if capture, err = strconv.ParseBool(captureEnv); err != nil {
+			return fmt.Errorf("invalid %s value %q: %w", EnvCapture, captureEnv, err)
+		}
+	}
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	if hasMaxDepth {
+		MaxStackDepth = uint(depth)
+	}
+	if hasCapture {
+		CaptureStack = capture
+	}
+	if pkg, ok := os.LookupEnv(EnvPackage); ok {
+		DefaultPackageName = pkg
+	}
+	if prefix, ok := os.LookupEnv(EnvTrimPrefix); ok {
+		TrimFilePrefix = prefix
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package stackerr
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigureFromEnv(t *testing.T) {
+	defer func() {
+		MaxStackDepth = 16
+		DefaultPackageName = ""
+		CaptureStack = true
+		TrimFilePrefix = ""
+	}()
+	t.Setenv(EnvMaxDepth, "32")
+	t.Setenv(EnvPackage, "mypkg")
+	t.Setenv(EnvCapture, "false")
+	t.Setenv(EnvTrimPrefix, "/src/")
+	err := ConfigureFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, uint(32), MaxStackDepth)
+	require.Equal(t, "mypkg", DefaultPackageName)
+	require.False(t, CaptureStack)
+	require.Equal(t, "/src/", TrimFilePrefix)
+}
+
+func TestConfigureFromEnv_Unset(t *testing.T) {
+	err := ConfigureFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, uint(16), MaxStackDepth)
+	require.Equal(t, "", DefaultPackageName)
+	require.True(t, CaptureStack)
+	require.Equal(t, "", TrimFilePrefix)
+}
+
+func TestConfigureFromEnv_Invalid(t *testing.T) {
+	t.Run("max depth", func(t *testing.T) {
+		t.Setenv(EnvMaxDepth, "-1")
+		t.Setenv(EnvPackage, "mypkg")
+		err := ConfigureFromEnv()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), EnvMaxDepth)
+		require.Equal(t, uint(16), MaxStackDepth)
+		require.Equal(t, "", DefaultPackageName)
+	})
+	t.Run("capture", func(t *testing.T) {
+		t.Setenv(EnvMaxDepth, "32")
+		t.Setenv(EnvCapture, "sometimes")
+		err := ConfigureFromEnv()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), EnvCapture)
+		require.Equal(t, uint(16), MaxStackDepth)
+		require.True(t, CaptureStack)
+	})
+}
@@ -0,0 +1,34 @@
+package stackerr
+
+func (e *err) Equal(other error) bool {
+	if o, ok := other.(StackError); ok && o != nil {
+		return e.Error() == o.Error() && e.code == o.Code()
+	}
+	return false
+}
+
+func (e *err) EqualWithStack(other error) bool {
+	if !e.Equal(other) {
+		return false
+	}
+	fr, ok := e.CallerFrame()
+	ofr, ook := other.(StackError).CallerFrame()
+	return ok == ook && fr.Function == ofr.Function && fr.File == ofr.File && fr.Line == ofr.Line
+}
+
+// SameError determines whether two errors are "the same error from the same line" - useful, for example, for
+// de-duplicating alerts
+//
+// If the first error is a StackError, SameError uses StackError.EqualWithStack - otherwise, the errors are the same if
+// they are both nil or both non-nil with the same message
+func SameError(a, b error) bool {
+	if se, ok := a.(StackError); ok && se != nil {
+		return se.EqualWithStack(b)
+	} else if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if _, ok := b.(StackError); ok {
+		return false
+	}
+	return a.Error() == b.Error()
+}
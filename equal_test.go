@@ -0,0 +1,48 @@
+package stackerr
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestError_Equal(t *testing.T) {
+	e1 := New("fooey")
+	e2 := New("fooey")
+	require.True(t, e1.Equal(e2))
+	require.True(t, e1.Equal(e1.WithField("foo", "bar")))
+	require.False(t, e1.Equal(New("other")))
+	require.False(t, e1.Equal(e2.WithCode("code")))
+	require.True(t, e1.WithCode("code").Equal(e2.WithCode("code")))
+	require.False(t, e1.Equal(errors.New("fooey")))
+	require.False(t, e1.Equal(nil))
+}
+
+func TestError_EqualWithStack(t *testing.T) {
+	errs := make([]StackError, 0, 2)
+	for range 2 {
+		errs = append(errs, New("fooey"))
+	}
+	other := New("fooey")
+	require.True(t, errs[0].EqualWithStack(errs[1]))
+	require.False(t, errs[0].EqualWithStack(other))
+	require.True(t, errs[0].Equal(other))
+	require.False(t, errs[0].EqualWithStack(errs[1].WithCode("code")))
+	require.False(t, errs[0].EqualWithStack(errors.New("fooey")))
+	require.False(t, errs[0].EqualWithStack(nil))
+}
+
+func TestSameError(t *testing.T) {
+	errs := make([]error, 0, 2)
+	for range 2 {
+		errs = append(errs, New("fooey"))
+	}
+	require.True(t, SameError(errs[0], errs[1]))
+	require.False(t, SameError(errs[0], New("fooey")))
+	require.False(t, SameError(errs[0], nil))
+	require.False(t, SameError(nil, errs[0]))
+	require.True(t, SameError(nil, nil))
+	require.True(t, SameError(errors.New("fooey"), errors.New("fooey")))
+	require.False(t, SameError(errors.New("fooey"), errors.New("other")))
+	require.False(t, SameError(errors.New("fooey"), errs[0]))
+}
@@ -1,10 +1,18 @@
 package stackerr
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"maps"
+	"math"
+	"reflect"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // StackError is an error interface with stack info
@@ -12,44 +20,358 @@ type StackError interface {
 	error
 	// WithCause returns a StackError with the cause set
 	WithCause(cause error) StackError
+	// WithCauses returns a StackError with multiple causes set (replacing any existing cause)
+	//
+	// errors.Is and errors.As match against any of the causes, Unwraps returns all the causes and
+	// Cause returns the first cause
+	//
+	// Note: Unwrap returns nil for errors with multiple causes (as Unwrap cannot return multiple errors)
+	WithCauses(causes ...error) StackError
+	// Clone returns an independent copy of the StackError
+	//
+	// Note: builder methods (e.g. WithCause, WithField) return copies that deliberately share the (immutable) stack info
+	// of the original - whereas Clone copies the stack info (and fields) so that nothing is shared
+	Clone() StackError
+	// WithField returns a StackError with the field key/value added
+	WithField(key string, value any) StackError
+	// WithFields returns a StackError with the fields added (overriding any existing fields with the same keys)
+	WithFields(fields map[string]any) StackError
+	// Fields returns the fields attached to the error (see WithField and WithFields)
+	//
+	// If MergeCauseFields is set (the default) and the cause is a StackError (e.g. when created by Wrap), the fields
+	// of the cause are merged in - where both carry the same key, the field of the outer (wrapping) error takes precedence
+	Fields() map[string]any
+	// WithValue returns a StackError with the value set for the key
+	//
+	// Unlike fields, values are not output when formatting (or marshalling) - they are intended for packages extending StackError
+	// (e.g. grpcstack) and, as with context.WithValue, the key should be of an unexported type to avoid collisions
+	WithValue(key, value any) StackError
+	// Value returns the value for the key (or nil if no value has been set for the key)
+	Value(key any) any
+	// WithCode returns a StackError with the code set
+	WithCode(code string) StackError
+	// Code returns the code of the error (or empty string if no code has been set)
+	Code() string
+	// WithTraceID returns a StackError with the trace ID set (e.g. the request ID or distributed trace ID of the request in which the error occurred)
+	WithTraceID(id string) StackError
+	// TraceID returns the trace ID of the error (or empty string if no trace ID has been set)
+	TraceID() string
+	// WithHTTPStatus returns a StackError with the HTTP status set
+	WithHTTPStatus(status int) StackError
+	// HTTPStatus returns the HTTP status of the error (or zero if no HTTP status has been set)
+	HTTPStatus() int
+	// WithExitCode returns a StackError with the process exit code set (see ExitCodeOf)
+	WithExitCode(code int) StackError
+	// ExitCode returns the process exit code of the error (or zero if no exit code has been set)
+	ExitCode() int
+	// WithSeverity returns a StackError with the severity set
+	WithSeverity(severity Severity) StackError
+	// Severity returns the severity of the error (SeverityError if no severity has been set)
+	Severity() Severity
+	// WithCategory returns a StackError with the category set
+	WithCategory(category Category) StackError
+	// Category returns the category of the error (or empty Category if no category has been set)
+	Category() Category
+	// WithTemporary returns a StackError with the temporary flag set (see IsTemporary)
+	WithTemporary(temporary bool) StackError
+	// Temporary returns whether the error is temporary (as with net.Error)
+	Temporary() bool
+	// WithTimeout returns a StackError with the timeout flag set (see IsTimeout)
+	WithTimeout(timeout bool) StackError
+	// Timeout returns whether the error is a timeout (as with net.Error)
+	Timeout() bool
+	// WithRetryable returns a StackError with the retryable flag set (see IsRetryable)
+	WithRetryable(retryable bool) StackError
+	// Retryable returns whether the error is retryable
+	Retryable() bool
+	// WithRetryAfter returns a StackError with the retry after duration set
+	WithRetryAfter(d time.Duration) StackError
+	// RetryAfter returns the duration after which the operation may be retried (or zero if not set)
+	RetryAfter() time.Duration
+	// WithMessage returns a StackError with the message prefixed (i.e. "prefix: message") - unlike Wrap, the stack info
+	// is not re-captured (so the origin is retained) and the cause is preserved
+	WithMessage(prefix string) StackError
+	// WithSentinel returns a StackError that matches the sentinel error (using errors.Is)
+	WithSentinel(sentinel error) StackError
+	// FullMessage returns the message of the error including the messages of the cause chain (as with formatting using %v)
+	//
+	// Note: Error() returns only the message of the error
+	FullMessage() string
 	Unwrap() error
+	// Unwraps returns all the causes of the error (e.g. the errors combined by Join)
+	Unwraps() []error
 	Cause() error
+	// RootCause returns the innermost cause of the error (or the error itself if it has no cause)
+	RootCause() error
 	// StackInfo returns the call stack info for the error
+	//
+	// The frames are always in capture order (innermost first) - regardless of StackOrderOuterFirst
 	StackInfo() StackInfo
+	// WithSyntheticFrame returns a StackError with a synthetic frame (for the function, file and line) prepended to the stack info
+	//
+	// This allows code that executes dynamically (e.g. templates or scripts) to attribute errors to a logical location
+	// that the runtime cannot see. The synthetic frame is formatted identically to real frames
+	WithSyntheticFrame(function string, file string, line int) StackError
+	// AppendStack returns a StackError whose stack info is the frames of the error followed by the frames of the other error
+	//
+	// This is useful to represent, for example, a failure that happened here - but was triggered by a failure captured
+	// elsewhere. When formatting, a boundary line is written between the frames (see BoundaryFrameFormatter)
+	AppendStack(other StackError) StackError
+	// Truncated returns whether the stack info was truncated (i.e. the actual call stack was deeper than the max depth captured)
+	//
+	// For stack info combined by AppendStack, Truncated is true if either of the stacks was truncated
+	Truncated() bool
+	// WithFrameFormatter returns a StackError that is formatted (with %+v) using the FrameFormatter - regardless of
+	// DefaultFrameFormatter (and FrameFormatterResolver)
+	//
+	// If the FrameFormatter is nil, the error is formatted as normal (i.e. using DefaultFrameFormatter)
+	WithFrameFormatter(f FrameFormatter) StackError
+	// WithoutStack returns a StackError with no stack info (e.g. before the error crosses an API boundary - see StripStack)
+	//
+	// The causes of the error are not affected
+	WithoutStack() StackError
+	// CauseStack returns the stack info of the innermost StackError in the cause chain (i.e. the origin of the error)
+	//
+	// returns nil if there is no StackError in the cause chain
+	CauseStack() StackInfo
+	// Frames returns an iterator over the call stack frames of the error (in the same order as StackInfo)
+	Frames() iter.Seq[runtime.Frame]
+	// StableFrames returns the call stack frames of the error mapped to Frame (a minimal, stable representation)
+	//
+	// Note: this is not named Frames, as Frames already returns an iterator over the runtime.Frame of the stack info
+	StableFrames() []Frame
+	// StackTrace returns the call stack info for the error as formatted lines
+	//
+	// Each line is formatted using DefaultFrameFormatter (trimmed) or, if DefaultFrameFormatter is nil, as "function (file:line)"
+	StackTrace() []string
+	// Goroutine returns the id of the goroutine that created the error (or zero if not captured - see CaptureGoroutineID)
+	Goroutine() uint64
+	// Timestamp returns the time at which the error was created (or zero time if not captured - see CaptureTimestamp)
+	Timestamp() time.Time
+	// SentryFrames returns the call stack info for the error as Sentry compatible frames
+	//
+	// Note: the frames are ordered innermost last (i.e. the reverse of StackInfo) - as expected by Sentry
+	SentryFrames() []SentryFrame
+	// SourceFrames returns the call stack frames of the error annotated with their source line text
+	// (the source line text is only populated when CaptureSourceLines was set when the error was created)
+	SourceFrames() []SourceFrame
+	// CallerFrame returns the originating frame of the error (i.e. the first frame of StackInfo)
+	//
+	// returns false if the stack info is empty
+	CallerFrame() (runtime.Frame, bool)
+	// Key returns the message key of the error (see NewKeyed) - or empty string if the error has no message key
+	Key() string
+	// MessageTemplate returns the original format and args of an error created with Newf
+	// (the args are only retained when CaptureMessageArgs is set)
+	//
+	// For errors not created with Newf, the format is the message and the args are nil
+	MessageTemplate() (format string, args []any)
+	// Fingerprint returns a stable hash of the error - for grouping occurrences of the same error (e.g. in error-tracking backends)
+	//
+	// The fingerprint is derived from the message (or the format, for errors created with Newf - so that differing
+	// args produce the same fingerprint), the code and the function and line of the top FingerprintDepth frames
+	Fingerprint() string
+	// Logfmt returns a single line logfmt (key=value) representation of the error
+	Logfmt() string
+	// ToMap returns a map representation of the error (see MarshalJSON)
+	ToMap() map[string]any
+	// Equal determines whether the other error is a StackError with the same message and code (the stack info is ignored)
+	Equal(other error) bool
+	// EqualWithStack determines whether the other error is a StackError with the same message and code - and the same
+	// originating frame (i.e. function, file and line of CallerFrame)
+	EqualWithStack(other error) bool
+	// Location returns the "file:line" of the originating frame of the error (or empty string if the stack info is empty)
+	Location() string
 }
 
 // New creates a new StackError with stack info
 func New(msg string) StackError {
-	return newError(msg, getStackInfo(), nil)
+	return newError(msg, getStackInfo(1, newOptions()), nil)
+}
+
+// NewWithOptions creates a new StackError with stack info, using the options supplied
+func NewWithOptions(msg string, opts ...Option) StackError {
+	return newError(msg, getStackInfo(1, newOptions(opts...)), nil)
 }
 
 // Newf creates a new StackError with stack info and a formatted message
 func Newf(format string, args ...any) StackError {
-	return newError(fmt.Sprintf(format, args...), getStackInfo(), nil)
+	e := allocError(fmt.Sprintf(format, args...), getStackInfo(1, newOptions()), nil)
+	e.format = format
+	if CaptureMessageArgs {
+		e.args = args
+	}
+	return e.created()
+}
+
+// NewWithCause creates a new StackError with stack info and the cause set
+// (equivalent to New(msg).WithCause(cause) - but with a single allocation)
+//
+// Note: unlike Wrap, a valid StackError is returned even if the cause is nil
+func NewWithCause(msg string, cause error) StackError {
+	return newError(msg, getStackInfo(1, newOptions()), cause)
+}
+
+// NewfWithCause creates a new StackError with stack info, a formatted message and the cause set
+// (equivalent to Newf(format, args...).WithCause(cause) - but with a single allocation)
+//
+// Note: unlike Wrap, a valid StackError is returned even if the cause is nil
+func NewfWithCause(cause error, format string, args ...any) StackError {
+	e := allocError(fmt.Sprintf(format, args...), getStackInfo(1, newOptions()), cause)
+	e.format = format
+	if CaptureMessageArgs {
+		e.args = args
+	}
+	return e.created()
 }
 
 // Wrap wraps an existing error with a StackError
 //
-// Note: the stack info is based on the point at which Wrap is called (rather than the callers of the wrapped error)
+// Note: the stack info is based on the point at which Wrap is called (rather than the callers of the wrapped error) - unless
+// PreserveCauseStack is set and the wrapped error is a StackError
 func Wrap(err error, msg string) StackError {
 	if err == nil {
 		return nil
+	} else if cs, ok := preservedStack(err); ok {
+		return newError(msg, cs, err)
 	}
-	return newError(msg, getStackInfo(), err)
+	return newError(msg, getStackInfo(1, newOptions()), err)
 }
 
-func newError(msg string, si StackInfo, cause error) StackError {
-	return &err{
-		message: msg,
-		stack:   si,
-		cause:   cause,
+// WrapWithOptions wraps an existing error with a StackError, using the options supplied
+//
+// Note: the stack info is based on the point at which WrapWithOptions is called (rather than the callers of the wrapped error) - unless
+// PreserveCauseStack is set and the wrapped error is a StackError
+func WrapWithOptions(err error, msg string, opts ...Option) StackError {
+	if err == nil {
+		return nil
+	} else if cs, ok := preservedStack(err); ok {
+		return newError(msg, cs, err)
 	}
+	return newError(msg, getStackInfo(1, newOptions(opts...)), err)
+}
+
+// WithStack wraps an existing error with a StackError - preserving the message of the existing error
+//
+// Note: the stack info is based on the point at which WithStack is called (rather than the callers of the wrapped error)
+func WithStack(err error) StackError {
+	if err == nil {
+		return nil
+	}
+	e := allocError(err.Error(), getStackInfo(1, newOptions()), err)
+	e.passthrough = true
+	return e.created()
+}
+
+func newError(msg string, si callStack, cause error) *err {
+	return allocError(msg, si, cause).created()
+}
+
+// allocError allocates a new error - without calling the creation hooks (see created)
+func allocError(msg string, si callStack, cause error) *err {
+	var e *err
+	if UsePool {
+		e = errPool.Get().(*err)
+	} else {
+		e = &err{}
+	}
+	e.message = truncateMessage(msg)
+	e.stack = si
+	e.cause = cause
+	if CaptureGoroutineID {
+		e.goroutine = goroutineID()
+	}
+	if CaptureTimestamp {
+		e.timestamp = time.Now()
+	}
+	if CaptureSourceLines {
+		e.stack.sources = captureSources(si.resolved())
+	}
+	return e
+}
+
+// created calls the creation hooks (see OnNew, OnErrorMetric and PanicOnNewMatching) for a newly allocated error
+func (e *err) created() *err {
+	if OnNew != nil {
+		OnNew(e)
+	}
+	if OnErrorMetric != nil {
+		OnErrorMetric(e.Fingerprint(), e)
+	}
+	if PanicOnNewMatching != nil && PanicOnNewMatching(e) {
+		panic(e)
+	}
+	return e
+}
+
+const truncatedSuffix = "..."
+
+// truncateMessage truncates the message (without splitting multibyte characters) if it exceeds MaxMessageLength
+//
+// the truncated message, including the suffix, fits within MaxMessageLength
+func truncateMessage(msg string) string {
+	if MaxMessageLength <= 0 || len(msg) <= MaxMessageLength {
+		return msg
+	} else if MaxMessageLength <= len(truncatedSuffix) {
+		return truncatedSuffix[:MaxMessageLength]
+	}
+	n := MaxMessageLength - len(truncatedSuffix)
+	for n > 0 && !utf8.RuneStart(msg[n]) {
+		n--
+	}
+	return msg[:n] + truncatedSuffix
 }
 
 type err struct {
-	message string
-	stack   StackInfo
-	cause   error
+	message    string
+	key        string
+	format     string
+	args       []any
+	stack      callStack
+	cause      error
+	sentinel   error
+	causes     []error
+	fields     map[string]any
+	values     map[any]any
+	code       string
+	traceID    string
+	httpStatus int
+	exitCode   int
+	severity   Severity
+	category   Category
+	temporary  flag
+	timeout    flag
+	retryable  flag
+	retryAfter time.Duration
+	goroutine  uint64
+	timestamp  time.Time
+	// joined indicates the causes were combined by Join (and the message is the messages of the causes)
+	joined bool
+	// passthrough indicates the message is that of the cause (see WithStack)
+	passthrough bool
+	// boundaries are the indexes of the stack frames at which appended stacks start (see AppendStack)
+	boundaries []int
+	// embedded indicates the message already includes the message of the cause (see WrapW)
+	embedded bool
+	// frameFormatter is the frame formatter used when formatting with %+v (see WithFrameFormatter)
+	frameFormatter FrameFormatter
+}
+
+// WrapW creates a new StackError with stack info and a formatted message - as with fmt.Errorf, the format may contain
+// a %w verb and the corresponding argument is used as the cause, e.g.
+//
+//	stackerr.WrapW("reading %s: %w", filename, err)
+//
+// The message is formatted exactly as with fmt.Errorf (i.e. it includes the message of the cause at the point of the %w) and
+// the cause is not repeated when the error is formatted (although, with %+v, the stack, metadata etc. of a StackError cause
+// are still written).
+// If the format contains no %w (or more than one %w) the cause is nil
+func WrapW(format string, args ...any) StackError {
+	w := fmt.Errorf(format, args...)
+	cause := errors.Unwrap(w)
+	e := allocError(w.Error(), getStackInfo(1, newOptions()), cause)
+	e.embedded = cause != nil
+	return e.created()
 }
 
 var _ error = (*err)(nil)
@@ -57,90 +379,647 @@ var _ StackError = (*err)(nil)
 var _ fmt.Formatter = (*err)(nil)
 
 func (e *err) Error() string {
-	return e.message
+	return e.text()
+}
+
+func (e *err) MessageTemplate() (string, []any) {
+	if e.format != "" {
+		return e.format, e.args
+	}
+	return e.message, nil
+}
+
+func (e *err) FullMessage() string {
+	if e.passthrough {
+		return fullMessage(e.cause)
+	} else if e.embedded {
+		return e.text()
+	} else if e.cause != nil {
+		return e.text() + CauseSeparator + fullMessage(e.cause)
+	} else if len(e.causes) > 0 && !e.joined {
+		msgs := make([]string, 0, len(e.causes))
+		for _, c := range e.causes {
+			msgs = append(msgs, fullMessage(c))
+		}
+		return e.text() + CauseSeparator + strings.Join(msgs, "; ")
+	}
+	return e.text()
+}
+
+func fullMessage(err error) string {
+	if err == nil {
+		return ""
+	} else if fm, ok := err.(interface{ FullMessage() string }); ok {
+		return fm.FullMessage()
+	}
+	return err.Error()
 }
 
 func (e *err) Unwrap() error {
 	return e.cause
 }
 
+func (e *err) Unwraps() []error {
+	if len(e.causes) > 0 {
+		return e.causes
+	} else if e.cause != nil {
+		return []error{e.cause}
+	}
+	return nil
+}
+
 func (e *err) Cause() error {
+	if e.cause == nil && len(e.causes) > 0 {
+		return e.causes[0]
+	}
 	return e.cause
 }
 
+func (e *err) RootCause() error {
+	return RootCause(e)
+}
+
 func (e *err) WithCause(cause error) StackError {
-	return &err{
-		message: e.message,
-		stack:   e.stack,
-		cause:   cause,
+	r := e.clone()
+	r.cause = cause
+	r.causes = nil
+	r.joined = false
+	r.embedded = false
+	r.passthrough = false
+	return r
+}
+
+func (e *err) WithCauses(causes ...error) StackError {
+	r := e.clone()
+	r.cause = nil
+	r.causes = make([]error, 0, len(causes))
+	for _, c := range causes {
+		if c != nil {
+			r.causes = append(r.causes, c)
+		}
+	}
+	if len(r.causes) == 0 {
+		r.causes = nil
 	}
+	r.joined = false
+	r.embedded = false
+	r.passthrough = false
+	return r
+}
+
+func (e *err) WithMessage(prefix string) StackError {
+	r := e.clone()
+	r.message = prefix + ": " + e.text()
+	r.key = ""
+	if e.passthrough {
+		// the message already includes the message of the cause (but %+v still writes the stack of a StackError cause)...
+		r.passthrough = false
+		r.embedded = true
+	}
+	return r
+}
+
+func (e *err) WithSentinel(sentinel error) StackError {
+	r := e.clone()
+	r.sentinel = sentinel
+	return r
+}
+
+// Is reports whether the target is the sentinel attached to the error (see StackError.WithSentinel)
+// or is found in any of the multiple causes (see Join and StackError.WithCauses)
+func (e *err) Is(target error) bool {
+	if e.sentinel != nil && target != nil && reflect.TypeOf(target).Comparable() && target == e.sentinel {
+		return true
+	}
+	for _, c := range e.causes {
+		if errors.Is(c, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error in the attached sentinel's chain (see StackError.WithSentinel)
+// or in any of the multiple causes (see Join and StackError.WithCauses) that matches target
+func (e *err) As(target any) bool {
+	if e.sentinel != nil && errors.As(e.sentinel, target) {
+		return true
+	}
+	for _, c := range e.causes {
+		if errors.As(c, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *err) Clone() StackError {
+	r := e.clone()
+	r.stack = callStack{frames: slices.Clone(e.StackInfo()), truncated: e.stack.truncated, sources: e.stack.sources}
+	r.fields = maps.Clone(e.fields)
+	r.causes = slices.Clone(e.causes)
+	return r
+}
+
+func (e *err) clone() *err {
+	r := *e
+	return &r
 }
 
 func (e *err) StackInfo() StackInfo {
-	return e.stack
+	return e.stack.resolved()
+}
+
+func (e *err) Truncated() bool {
+	return e.stack.truncated
+}
+
+func (e *err) WithoutStack() StackError {
+	r := e.clone()
+	r.stack = callStack{}
+	r.boundaries = nil
+	return r
+}
+
+// StripStack returns the error with the stack info removed from it and from each StackError in its cause chain
+// (including multiple causes) - e.g. before returning errors to untrusted clients
+//
+// StackErrors are rebuilt without stack info (see StackError.WithoutStack) - the original errors are not modified.
+// Note: the chain is only walked through StackErrors - other errors (e.g. created by fmt.Errorf with %w) are returned as is,
+// so any StackError wrapped by them retains its stack info
+func StripStack(err error) error {
+	return stripStack(err, 0)
+}
+
+func stripStack(e error, depth int) error {
+	se, ok := e.(*err)
+	if !ok || depth >= maxChainLength {
+		return e
+	}
+	r := se.WithoutStack().(*err)
+	if r.cause != nil {
+		r.cause = stripStack(r.cause, depth+1)
+	}
+	if len(r.causes) > 0 {
+		causes := make([]error, 0, len(r.causes))
+		for _, c := range r.causes {
+			causes = append(causes, stripStack(c, depth+1))
+		}
+		r.causes = causes
+	}
+	return r
+}
+
+func (e *err) WithFrameFormatter(f FrameFormatter) StackError {
+	r := e.clone()
+	r.frameFormatter = f
+	return r
+}
+
+func (e *err) WithSyntheticFrame(function string, file string, line int) StackError {
+	r := e.clone()
+	si := e.StackInfo()
+	frames := make(StackInfo, 0, len(si)+1)
+	frames = append(frames, runtime.Frame{Function: function, File: file, Line: line})
+	r.stack = callStack{frames: append(frames, si...), truncated: e.stack.truncated, sources: e.stack.sources}
+	r.boundaries = make([]int, 0, len(e.boundaries))
+	for _, b := range e.boundaries {
+		r.boundaries = append(r.boundaries, b+1)
+	}
+	return r
+}
+
+func (e *err) AppendStack(other StackError) StackError {
+	r := e.clone()
+	if other == nil {
+		return r
+	}
+	si, osi := e.StackInfo(), other.StackInfo()
+	frames := make(StackInfo, 0, len(si)+len(osi))
+	r.stack = callStack{frames: append(append(frames, si...), osi...), truncated: e.stack.truncated || other.Truncated(), sources: e.stack.sources}
+	r.boundaries = append(slices.Clone(e.boundaries), len(si))
+	if o, ok := other.(*err); ok {
+		r.stack.sources = mergeSources(r.stack.sources, o.stack.sources)
+		for _, b := range o.boundaries {
+			r.boundaries = append(r.boundaries, len(si)+b)
+		}
+	}
+	return r
+}
+
+// writeBoundary writes the boundary line (see StackError.AppendStack) if the frame index is at a boundary
+func writeBoundary(w io.Writer, bf BoundaryFrameFormatter, boundaries []int, idx int) {
+	if bf != nil && slices.Contains(boundaries, idx) {
+		_, _ = io.WriteString(w, bf.BoundaryLine())
+	}
+}
+
+func (e *err) CauseStack() StackInfo {
+	var result StackInfo
+	cause := e.Cause()
+	for i := 0; cause != nil && i < maxChainLength; i++ {
+		if se, ok := cause.(interface{ StackInfo() StackInfo }); ok {
+			result = se.StackInfo()
+		}
+		cause = errors.Unwrap(cause)
+	}
+	return result
+}
+
+// preservedStack returns the call stack of the cause - if PreserveCauseStack is set and the cause is a StackError
+func preservedStack(cause error) (callStack, bool) {
+	if PreserveCauseStack {
+		if e, ok := cause.(*err); ok {
+			return e.stack, true
+		}
+	}
+	return callStack{}, false
+}
+
+func (e *err) Goroutine() uint64 {
+	return e.goroutine
+}
+
+func (e *err) Timestamp() time.Time {
+	return e.timestamp
+}
+
+func (e *err) CallerFrame() (runtime.Frame, bool) {
+	if si := e.StackInfo(); len(si) > 0 {
+		return si[0], true
+	}
+	return runtime.Frame{}, false
+}
+
+func (e *err) Location() string {
+	if fr, ok := e.CallerFrame(); ok {
+		return fmt.Sprintf("%s:%d", FrameFile(fr), fr.Line)
+	}
+	return ""
+}
+
+func (e *err) StableFrames() []Frame {
+	return e.StackInfo().StableFrames()
+}
+
+func (e *err) Frames() iter.Seq[runtime.Frame] {
+	return func(yield func(runtime.Frame) bool) {
+		for _, fr := range e.StackInfo() {
+			if !yield(fr) {
+				return
+			}
+		}
+	}
+}
+
+func (e *err) StackTrace() []string {
+	si := e.StackInfo()
+	result := make([]string, 0, len(si))
+	ff := DefaultFrameFormatterValue()
+	for _, fr := range si {
+		if ff != nil {
+			result = append(result, strings.TrimSpace(ff.FrameLine(fr)))
+		} else {
+			result = append(result, fmt.Sprintf("%s (%s:%d)", fr.Function, FrameFile(fr), fr.Line))
+		}
+	}
+	return result
 }
 
 func (e *err) Format(f fmt.State, verb rune) {
 	switch verb {
 	case 'v':
-		_, _ = fmt.Fprintf(f, "%s", e.message)
 		if f.Flag('+') {
-			if e.cause != nil {
-				_, _ = fmt.Fprintf(f, ": %+v", e.cause)
-			}
-			if len(e.stack) > 0 && DefaultFrameFormatter != nil {
-				_, _ = io.WriteString(f, DefaultFrameFormatter.StartLine())
-				for _, fr := range e.stack {
-					_, _ = io.WriteString(f, DefaultFrameFormatter.FrameLine(fr))
-				}
-			}
-		} else if e.cause != nil {
-			_, _ = fmt.Fprintf(f, ": %v", e.cause)
+			e.writeVerbose(f, 0)
+		} else {
+			e.writeMessage(f, false, 0)
+			e.writeStack(f, resolveFrameFormatter(verb, false))
 		}
 	case 's':
-		_, _ = io.WriteString(f, e.message)
+		_, _ = io.WriteString(f, e.text())
+		if f.Flag('+') {
+			if loc := e.Location(); loc != "" {
+				_, _ = fmt.Fprintf(f, " (%s)", loc)
+			}
+		}
 	case 'q':
-		_, _ = fmt.Fprintf(f, "%q", e.message)
+		// %+q quotes the message including the messages of the cause chain (as with %v) - plain %q quotes only the message
+		if f.Flag('+') {
+			_, _ = fmt.Fprintf(f, "%q", e.FullMessage())
+		} else {
+			_, _ = fmt.Fprintf(f, "%q", e.text())
+		}
 	default:
-		_, _ = io.WriteString(f, "%!")
-		_, _ = io.WriteString(f, string(verb))
-		_, _ = io.WriteString(f, "(stackerr.err)")
+		if ff := resolveFrameFormatter(verb, f.Flag('+')); ff != nil {
+			e.writeMessage(f, false, 0)
+			e.writeStack(f, ff)
+		} else {
+			_, _ = io.WriteString(f, "%!")
+			_, _ = io.WriteString(f, string(verb))
+			_, _ = io.WriteString(f, "(stackerr.err)")
+		}
+	}
+}
+
+func resolveFrameFormatter(verb rune, plus bool) FrameFormatter {
+	if FrameFormatterResolver != nil {
+		if ff := FrameFormatterResolver(verb, plus); ff != nil {
+			return ff
+		}
+	}
+	if verb == 'v' && plus {
+		return DefaultFrameFormatterValue()
+	}
+	return nil
+}
+
+func (e *err) writeStack(w io.Writer, ff FrameFormatter) {
+	writeFrames(w, e.StackInfo(), ff, e.boundaries, e.stack.truncated, e.stack.sources)
+}
+
+// writeFrames writes the stack info using the frame formatter - the boundaries are the indexes of the frames at which
+// appended stacks start (see StackError.AppendStack), truncated indicates whether frames were dropped (see StackError.Truncated)
+// and sources are the captured source lines of the frames (see CaptureSourceLines)
+func writeFrames(w io.Writer, si StackInfo, ff FrameFormatter, boundaries []int, truncated bool, sources map[sourcePos]string) {
+	if FormatFrameFilter != nil {
+		si, boundaries = filterStack(si, boundaries, FormatFrameFilter)
+	}
+	if len(si) > 0 && ff != nil {
+		if StackOrderOuterFirst {
+			si, boundaries = reverseStack(si, boundaries)
+		}
+		// the separator and end line are obtained as they are written (rather than up front) - as formatters
+		// may have side effects (see TeeFormatter)...
+		xf, _ := ff.(ExtendedFrameFormatter)
+		sf, _ := ff.(SourceFrameFormatter)
+		bf, _ := ff.(BoundaryFrameFormatter)
+		_, _ = io.WriteString(w, ff.StartLine())
+		tf, _ := ff.(TruncatedFrameFormatter)
+		if tf != nil && truncated && StackOrderOuterFirst {
+			// the dropped frames are outermost - so the marker precedes the frames...
+			_, _ = io.WriteString(w, tf.TruncatedLine())
+		}
+		if rf, ok := repeatedFormatter(ff); ok && CollapseRecursion {
+			idx := 0
+			for i, cf := range si.collapsed(boundaries) {
+				if i > 0 && xf != nil {
+					_, _ = io.WriteString(w, xf.FrameSeparator())
+				}
+				writeBoundary(w, bf, boundaries, idx)
+				if cf.Repeats > 1 {
+					_, _ = io.WriteString(w, rf.RepeatedFrameLine(cf.Frame, cf.Repeats))
+				} else {
+					_, _ = io.WriteString(w, ff.FrameLine(cf.Frame))
+				}
+				writeSourceLine(w, sf, sources, cf.Frame)
+				idx += cf.Repeats
+			}
+		} else {
+			for i, fr := range si {
+				if i > 0 && xf != nil {
+					_, _ = io.WriteString(w, xf.FrameSeparator())
+				}
+				writeBoundary(w, bf, boundaries, i)
+				_, _ = io.WriteString(w, ff.FrameLine(fr))
+				writeSourceLine(w, sf, sources, fr)
+			}
+		}
+		if tf != nil && truncated && !StackOrderOuterFirst {
+			_, _ = io.WriteString(w, tf.TruncatedLine())
+		}
+		if xf != nil {
+			_, _ = io.WriteString(w, xf.EndLine())
+		}
+	}
+}
+
+// repeatedFormatter returns the frame formatter as a RepeatedFrameFormatter - if it supports collapsed frames
+func repeatedFormatter(ff FrameFormatter) (RepeatedFrameFormatter, bool) {
+	rf, ok := ff.(RepeatedFrameFormatter)
+	if r, is := ff.(interface{ repeats() bool }); ok && is {
+		ok = r.repeats()
+	}
+	return rf, ok
+}
+
+// filterStack returns the frames of the stack info for which the include func returns true (and the boundaries of appended
+// stacks adjusted accordingly) - the stack info supplied is not modified
+//
+// boundaries that no longer fall between frames (i.e. where all the frames before, or after, have been filtered out) are dropped -
+// and boundaries between which all the frames have been filtered out are combined
+func filterStack(si StackInfo, boundaries []int, include func(runtime.Frame) bool) (StackInfo, []int) {
+	fsi := make(StackInfo, 0, len(si))
+	var fb []int
+	pending := false
+	for i, fr := range si {
+		pending = pending || slices.Contains(boundaries, i)
+		if include(fr) {
+			if pending && len(fsi) > 0 {
+				fb = append(fb, len(fsi))
+			}
+			pending = false
+			fsi = append(fsi, fr)
+		}
+	}
+	return fsi, fb
+}
+
+// reverseStack returns the stack info (and the boundaries of appended stacks) in reverse order - the stack info supplied is not modified
+func reverseStack(si StackInfo, boundaries []int) (StackInfo, []int) {
+	rsi := slices.Clone(si)
+	slices.Reverse(rsi)
+	var rb []int
+	if len(boundaries) > 0 {
+		rb = make([]int, 0, len(boundaries))
+		for i := len(boundaries) - 1; i >= 0; i-- {
+			rb = append(rb, len(si)-boundaries[i])
+		}
+	}
+	return rsi, rb
+}
+
+func writeCause(w io.Writer, cause error, plus bool, depth int) {
+	if cause == nil {
+		return
+	} else if !plus {
+		_, _ = fmt.Fprintf(w, "%v", cause)
+	} else if ce, ok := cause.(*err); ok {
+		ce.writeVerbose(w, depth)
+	} else {
+		_, _ = fmt.Fprintf(w, "%+v", cause)
+	}
+}
+
+// writeVerbose writes the error as with %+v - the depth is the number of cause levels already descended (see MaxCauseDepth)
+func (e *err) writeVerbose(w io.Writer, depth int) {
+	e.writeMessage(w, true, depth)
+	e.writeDetail(w)
+}
+
+// writeDetail writes the verbose detail of the error (metadata, fields, causes and stack) - i.e. everything but the message
+func (e *err) writeDetail(w io.Writer) {
+	e.writeMetadata(w)
+	e.writeFields(w)
+	e.writeCauses(w)
+	if e.frameFormatter != nil {
+		e.writeStack(w, e.frameFormatter)
+	} else {
+		e.writeStack(w, resolveFrameFormatter('v', true))
+	}
+}
+
+func (e *err) writeMessage(w io.Writer, plus bool, depth int) {
+	if e.passthrough {
+		writeCause(w, e.cause, plus, depth)
+		return
+	}
+	_, _ = io.WriteString(w, e.text())
+	if e.embedded {
+		if plus {
+			writeEmbeddedDetail(w, e.cause, depth)
+		}
+	} else if e.cause != nil {
+		_, _ = io.WriteString(w, CauseSeparator)
+		if plus && MaxCauseDepth > 0 && depth >= MaxCauseDepth {
+			_, _ = fmt.Fprintf(w, "... (%d more)", len(Causes(e.cause)))
+		} else {
+			writeCause(w, e.cause, plus, depth+1)
+		}
+	} else if !plus && !e.joined {
+		for i, c := range e.causes {
+			if i == 0 {
+				_, _ = io.WriteString(w, CauseSeparator)
+			} else {
+				_, _ = io.WriteString(w, "; ")
+			}
+			_, _ = fmt.Fprintf(w, "%v", c)
+		}
+	}
+}
+
+// writeEmbeddedDetail writes the verbose detail of a cause whose message is already embedded in the message of the
+// wrapping error (see WrapW) - so that the stacks (and metadata etc.) of the cause and its own causes are not lost
+func writeEmbeddedDetail(w io.Writer, cause error, depth int) {
+	if ce, ok := cause.(*err); ok && (MaxCauseDepth <= 0 || depth < MaxCauseDepth) {
+		writeEmbeddedDetail(w, ce.cause, depth+1)
+		ce.writeDetail(w)
+	}
+}
+
+func (e *err) writeCauses(w io.Writer) {
+	if len(e.causes) > 0 && !e.joined {
+		_, _ = io.WriteString(w, "\nCauses:")
+		for i, c := range e.causes {
+			_, _ = fmt.Fprintf(w, "\n\t%d. %v", i+1, c)
+		}
 	}
 }
 
 type StackInfo []runtime.Frame
 
-func getStackInfo() StackInfo {
-	result := make(StackInfo, 0, MaxStackDepth)
-	const skip = 3
-	pc := make([]uintptr, MaxStackDepth)
-	n := runtime.Callers(skip, pc)
-	frames := runtime.CallersFrames(pc[:n])
-	for frame, more := frames.Next(); more && len(result) < int(MaxStackDepth); frame, more = frames.Next() {
-		if DefaultPackageFilter != nil || DefaultPackageName != "" {
-			full, short := packageFromFunction(frame.Function)
-			if DefaultPackageFilter != nil && !DefaultPackageFilter.Include(full) {
-				continue
+// getStackInfo captures the call stack of the caller of the stackerr entry point (e.g. New, Wrap)
+//
+// the entryDepth is the number of stackerr frames between the attributed caller and getStackInfo - i.e. 1 when called
+// directly from a public entry point, 2 when called from a helper that is called by the public entry point, etc.
+// (in addition to which, o.skip frames are skipped)
+func getStackInfo(entryDepth int, o options) callStack {
+	if !CaptureStackValue() {
+		return callStack{frames: StackInfo{}}
+	}
+	// skip runtime.Callers and getStackInfo itself - plus the stackerr entry frames...
+	skip := 2 + entryDepth
+	var pc []uintptr
+	var n int
+	if o.maxDepth == 0 {
+		// unlimited depth - grow the buffer until it holds the entire stack...
+		for size := unlimitedInitialDepth; ; size *= 2 {
+			pc = make([]uintptr, size)
+			if n = runtime.Callers(skip+o.skip, pc); n < size {
+				break
 			}
-			if DefaultPackageName != "" && DefaultPackageName != short {
+		}
+	} else {
+		// capture one more than the max depth - to determine whether the stack is truncated...
+		pc = make([]uintptr, o.maxDepth+1)
+		n = runtime.Callers(skip+o.skip, pc)
+	}
+	truncated := o.maxDepth > 0 && n > int(o.maxDepth)
+	if truncated {
+		n = int(o.maxDepth)
+	}
+	if LazyStack {
+		return callStack{
+			lazy: &lazyFrames{
+				pcs:  pc[:n],
+				opts: o,
+			},
+			truncated: truncated,
+		}
+	}
+	return callStack{frames: resolveFrames(pc[:n], o), truncated: truncated}
+}
+
+const unlimitedInitialDepth = 64
+
+func resolveFrames(pcs []uintptr, o options) StackInfo {
+	limit := int(o.maxDepth)
+	if limit == 0 {
+		limit = math.MaxInt
+	}
+	result := make(StackInfo, 0, min(limit, len(pcs)))
+	trimming := o.trimRuntime
+	filter, pkgName := packageSettings()
+	if !trimming && filter == nil && pkgName == "" && !ExcludeSelf {
+		// fast path - no frames are excluded...
+		for i := 0; i < len(pcs) && len(result) < limit; i++ {
+			result = append(result, symbolize(pcs[i]).frame)
+		}
+		return result
+	}
+	for i := 0; i < len(pcs) && len(result) < limit; i++ {
+		sym := symbolize(pcs[i])
+		if trimming {
+			if sym.pkg == "runtime" {
 				continue
 			}
+			trimming = false
+		}
+		if ExcludeSelf && isSelfFrame(sym.frame) {
+			continue
 		}
-		result = append(result, frame)
+		if filter != nil && !filter.Include(sym.pkg) {
+			continue
+		}
+		if pkgName != "" && pkgName != sym.shortPkg {
+			continue
+		}
+		result = append(result, sym.frame)
 	}
 	return result
 }
 
+// packageFromFunction determines the full package path and short package name from a frame function name
+//
+// dots in the last path segment of a package (e.g. "gopkg.in/yaml.v3") are always escaped by the runtime as "%2e" - so
+// the package path ends at the first unescaped dot after the last slash (and is then unescaped)
+//
+// the short package name has any version suffix removed (e.g. "gopkg.in/yaml.v3" has short name "yaml")
+//
+// method receivers (e.g. "pkg.(*T).M") and generic type args (e.g. "pkg.Func[...]") are ignored - as they
+// cannot be part of the package path but may contain slashes and dots
 func packageFromFunction(name string) (full string, short string) {
-	full = name
-	if s := strings.LastIndexByte(full, '/'); s >= 0 {
-		if d := strings.IndexByte(name[s+1:], '.'); d >= 0 {
-			short = name[s+1 : s+d+1]
-		}
-		full = full[:s+1] + short
-	} else if d := strings.IndexByte(name, '.'); d >= 0 {
-		full = full[:d]
-		short = full
+	if i := strings.IndexAny(name, "[("); i >= 0 {
+		name = name[:i]
+	}
+	s := strings.LastIndexByte(name, '/') + 1
+	end := len(name)
+	if d := strings.IndexByte(name[s:], '.'); d >= 0 {
+		end = s + d
+	}
+	full = strings.ReplaceAll(name[:end], "%2e", ".")
+	short = full[strings.LastIndexByte(full, '/')+1:]
+	if d := strings.IndexByte(short, '.'); d >= 0 {
+		short = short[:d]
 	}
 	return full, short
 }
@@ -180,3 +180,753 @@ func TestPackageFromFunction(t *testing.T) {
 	require.Equal(t, "stackerr", full)
 	require.Equal(t, "stackerr", short)
 }
+
+func TestError_WithSentinel(t *testing.T) {
+	sentinel := New("not found")
+	e := New("user not found").WithSentinel(sentinel)
+	require.Equal(t, "user not found", e.Error())
+	require.True(t, errors.Is(e, sentinel))
+	require.False(t, errors.Is(e, New("not found")))
+	require.False(t, errors.Is(New("user not found"), sentinel))
+
+	t.Run("through wrap chain", func(t *testing.T) {
+		w := Wrap(Wrap(e, "level 1"), "level 2")
+		require.True(t, errors.Is(w, sentinel))
+		w2 := fmt.Errorf("level 3: %w", w)
+		require.True(t, errors.Is(w2, sentinel))
+	})
+	t.Run("sentinel preserved by WithCause", func(t *testing.T) {
+		e2 := e.WithCause(errors.New("cause"))
+		require.True(t, errors.Is(e2, sentinel))
+	})
+	t.Run("As", func(t *testing.T) {
+		e := New("fooey").WithSentinel(&testSentinel{code: 42})
+		w := Wrap(e, "wrapped")
+		var ts *testSentinel
+		require.True(t, errors.As(w, &ts))
+		require.Equal(t, 42, ts.code)
+		require.False(t, errors.As(New("fooey"), &ts))
+	})
+}
+
+type testSentinel struct {
+	code int
+}
+
+func (ts *testSentinel) Error() string {
+	return "sentinel"
+}
+
+func TestError_Unwraps(t *testing.T) {
+	e := New("fooey")
+	require.Nil(t, e.Unwraps())
+	e = e.WithCause(errors.New("cause"))
+	require.Len(t, e.Unwraps(), 1)
+	require.Equal(t, "cause", e.Unwraps()[0].Error())
+}
+
+func TestError_StackTrace(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey")
+	st := e.StackTrace()
+	require.Len(t, st, 1)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestError_StackTrace:233", st[0])
+
+	t.Run("without DefaultFrameFormatter", func(t *testing.T) {
+		DefaultFrameFormatter = nil
+		defer func() {
+			DefaultFrameFormatter = &frameFormatter{}
+		}()
+		st := e.StackTrace()
+		require.Len(t, st, 1)
+		require.True(t, strings.HasPrefix(st[0], "github.com/go-andiamo/stackerr.TestError_StackTrace ("))
+		require.True(t, strings.HasSuffix(st[0], "error_test.go:233)"))
+	})
+	t.Run("empty stack", func(t *testing.T) {
+		st := NewWithOptions("fooey", WithSkip(1000)).StackTrace()
+		require.NotNil(t, st)
+		require.Empty(t, st)
+	})
+}
+
+func TestWithStack(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	cause := errors.New("cause")
+	e := WithStack(cause)
+	require.Error(t, e)
+	require.Equal(t, "cause", e.Error())
+	require.Equal(t, cause, e.Cause())
+	require.Equal(t, cause, e.Unwrap())
+	require.True(t, errors.Is(e, cause))
+	require.Equal(t, "cause", fmt.Sprintf("%v", e))
+	require.Equal(t, "cause", fmt.Sprintf("%s", e))
+	si := e.StackInfo()
+	require.Len(t, si, 1)
+	require.Equal(t, 261, si[0].Line)
+	out := fmt.Sprintf("%+v", e)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, "cause", lines[0])
+	require.Equal(t, "Stack:", lines[1])
+
+	require.NoError(t, WithStack(nil))
+}
+
+func TestError_CallerFrame(t *testing.T) {
+	e := New("fooey")
+	fr, ok := e.CallerFrame()
+	require.True(t, ok)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestError_CallerFrame", fr.Function)
+	require.Equal(t, 282, fr.Line)
+	require.True(t, strings.HasSuffix(e.Location(), "error_test.go:282"))
+
+	e = NewWithOptions("fooey", WithSkip(1000))
+	fr, ok = e.CallerFrame()
+	require.False(t, ok)
+	require.Equal(t, "", fr.Function)
+	require.Equal(t, 0, fr.Line)
+	require.Equal(t, "", e.Location())
+}
+
+func TestPackageFromFunction_Versioned(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expectFull  string
+		expectShort string
+	}{
+		{
+			name:        "gopkg.in/yaml.v3.Marshal",
+			expectFull:  "gopkg.in/yaml",
+			expectShort: "yaml",
+		},
+		{
+			name:        "gopkg.in/yaml%2ev3.Marshal",
+			expectFull:  "gopkg.in/yaml.v3",
+			expectShort: "yaml",
+		},
+		{
+			name:        "gopkg.in/yaml%2ev3.(*encoder).marshal",
+			expectFull:  "gopkg.in/yaml.v3",
+			expectShort: "yaml",
+		},
+		{
+			name:        "github.com/foo/bar%2ev2.(*T).M",
+			expectFull:  "github.com/foo/bar.v2",
+			expectShort: "bar",
+		},
+		{
+			name:        "github.com/foo/bar%2ev2.T.M.func1",
+			expectFull:  "github.com/foo/bar.v2",
+			expectShort: "bar",
+		},
+		{
+			name:        "github.com/foo/bar.Func.func1",
+			expectFull:  "github.com/foo/bar",
+			expectShort: "bar",
+		},
+		{
+			name:        "github.com/foo/bar.(*T).M-fm",
+			expectFull:  "github.com/foo/bar",
+			expectShort: "bar",
+		},
+		{
+			name:        "github.com/foo/bar.vital",
+			expectFull:  "github.com/foo/bar",
+			expectShort: "bar",
+		},
+		{
+			name:        "github.com/foo/v2.Func",
+			expectFull:  "github.com/foo/v2",
+			expectShort: "v2",
+		},
+		{
+			name:        "main.main",
+			expectFull:  "main",
+			expectShort: "main",
+		},
+		{
+			name:        "main.v1",
+			expectFull:  "main",
+			expectShort: "main",
+		},
+		{
+			name:        "github.com/x/api.v1.func1",
+			expectFull:  "github.com/x/api",
+			expectShort: "api",
+		},
+		{
+			name:        "github.com/foo/bar",
+			expectFull:  "github.com/foo/bar",
+			expectShort: "bar",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("[%d]%s", i+1, tc.name), func(t *testing.T) {
+			full, short := packageFromFunction(tc.name)
+			require.Equal(t, tc.expectFull, full)
+			require.Equal(t, tc.expectShort, short)
+		})
+	}
+}
+
+func TestPackageFromFunction_SymbolShapes(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expectFull  string
+		expectShort string
+	}{
+		{
+			name:        "github.com/org/pkg.(*T).M",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.T.M",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.Func[...]",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.Func[go.shape.int]",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.Func[github.com/other/types.Thing]",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.(*T[go.shape.string]).M",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.(*T[...]).M.func1.2",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "github.com/org/pkg.Func.func1.2.3",
+			expectFull:  "github.com/org/pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "pkg.(*T).M",
+			expectFull:  "pkg",
+			expectShort: "pkg",
+		},
+		{
+			name:        "gopkg.in/yaml%2ev3.(*encoder).marshal.func1",
+			expectFull:  "gopkg.in/yaml.v3",
+			expectShort: "yaml",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("[%d]%s", i+1, tc.name), func(t *testing.T) {
+			full, short := packageFromFunction(tc.name)
+			require.Equal(t, tc.expectFull, full)
+			require.Equal(t, tc.expectShort, short)
+		})
+	}
+	t.Run("runtime symbols", func(t *testing.T) {
+		DefaultPackageName = "stackerr"
+		defer func() {
+			DefaultPackageName = ""
+		}()
+		errs := []StackError{
+			testGenericNew[int](),
+			testGenericNew[testShapes](),
+			(&testShapes{}).pointerMethod(),
+			testShapes{}.valueMethod(),
+			(&testGenericType[string]{}).method(),
+		}
+		for _, e := range errs {
+			si := e.StackInfo()
+			require.NotEmpty(t, si)
+			full, short := packageFromFunction(si[0].Function)
+			require.Equal(t, "github.com/go-andiamo/stackerr", full)
+			require.Equal(t, "stackerr", short)
+		}
+	})
+}
+
+func testGenericNew[T any]() StackError {
+	return func() StackError {
+		return func() StackError {
+			return New("fooey")
+		}()
+	}()
+}
+
+type testShapes struct{}
+
+func (ts *testShapes) pointerMethod() StackError {
+	return func() StackError {
+		return New("fooey")
+	}()
+}
+
+func (ts testShapes) valueMethod() StackError {
+	return New("fooey")
+}
+
+type testGenericType[T any] struct{}
+
+func (gt *testGenericType[T]) method() StackError {
+	return func() StackError {
+		return New("fooey")
+	}()
+}
+
+func TestError_Clone(t *testing.T) {
+	e := New("fooey").WithField("foo", 1).WithCode("CODE")
+	c := e.Clone()
+	require.Equal(t, e.Error(), c.Error())
+	require.Equal(t, e.Code(), c.Code())
+	require.Equal(t, e.Fields(), c.Fields())
+	require.Equal(t, e.StackInfo(), c.StackInfo())
+	require.NotSame(t, &e.StackInfo()[0], &c.StackInfo()[0])
+	require.Same(t, &e.StackInfo()[0], &e.WithCause(nil).StackInfo()[0])
+
+	c.(*err).fields["foo"] = 2
+	c.(*err).stack.frames[0].Line = 0
+	require.Equal(t, 1, e.Fields()["foo"])
+	require.NotEqual(t, 0, e.StackInfo()[0].Line)
+
+	c = c.WithField("bar", 3)
+	require.Nil(t, e.Fields()["bar"])
+}
+
+func TestError_FullMessage(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, "fooey", e.FullMessage())
+	e = Wrap(Wrap(errors.New("root"), "inner"), "outer")
+	require.Equal(t, "outer", e.Error())
+	require.Equal(t, "outer: inner: root", e.FullMessage())
+	require.Equal(t, fmt.Sprintf("%v", e), e.FullMessage())
+	e = Wrap(fmt.Errorf("plain: %w", New("inner").WithCause(errors.New("root"))), "outer")
+	require.Equal(t, fmt.Sprintf("%v", e), e.FullMessage())
+	e = WithStack(Wrap(errors.New("root"), "inner"))
+	require.Equal(t, "inner: root", e.FullMessage())
+	require.Equal(t, fmt.Sprintf("%v", e), e.FullMessage())
+}
+
+func TestOnNew(t *testing.T) {
+	var created []StackError
+	OnNew = func(err StackError) {
+		created = append(created, err)
+	}
+	defer func() {
+		OnNew = nil
+	}()
+	e1 := New("fooey")
+	e2 := Newf("fooey %d", 2)
+	e3 := Wrap(errors.New("cause"), "fooey")
+	require.NoError(t, Wrap(nil, "fooey"))
+	_ = e1.WithCause(nil)
+	require.Len(t, created, 3)
+	require.Equal(t, e1, created[0])
+	require.Equal(t, e2, created[1])
+	require.Equal(t, e3, created[2])
+}
+
+func TestError_Frames(t *testing.T) {
+	e := recurse(3, func() StackError {
+		return New("fooey")
+	})
+	si := e.StackInfo()
+	require.Greater(t, len(si), 3)
+	i := 0
+	for fr := range e.Frames() {
+		require.Equal(t, si[i], fr)
+		i++
+	}
+	require.Equal(t, len(si), i)
+
+	i = 0
+	for fr := range e.Frames() {
+		i++
+		if strings.HasSuffix(fr.Function, ".recurse") {
+			break
+		}
+	}
+	require.Equal(t, 2, i)
+}
+
+func TestMaxMessageLength(t *testing.T) {
+	MaxMessageLength = 5
+	defer func() {
+		MaxMessageLength = 0
+	}()
+	require.Equal(t, "fooe", New("fooe").Error())
+	require.Equal(t, "fooey", New("fooey").Error())
+	require.Equal(t, "fo...", New("fooey!").Error())
+	require.Equal(t, "fo...", Newf("fooey %d", 123).Error())
+	require.Equal(t, "fo...", Wrap(errors.New("cause"), "fooey fooey").Error())
+	// multibyte chars...
+	require.Equal(t, "fooé", New("fooé").Error())
+	require.Equal(t, "fo...", New("fooéy").Error())
+	require.Equal(t, "...", New("日本語").Error())
+	MaxMessageLength = 7
+	require.Equal(t, "foo...", New("fooéyyy").Error())
+	require.Equal(t, "fooo...", New("foooéyy").Error())
+	require.Equal(t, "日...", New("日本語").Error())
+	// limit shorter than the suffix...
+	MaxMessageLength = 2
+	require.Equal(t, "..", New("fooey").Error())
+
+	MaxMessageLength = 0
+	require.Equal(t, "fooey fooey", New("fooey fooey").Error())
+}
+
+func TestError_Format_PlusS(t *testing.T) {
+	e := New("fooey")
+	fr, ok := e.CallerFrame()
+	require.True(t, ok)
+	require.Equal(t, fmt.Sprintf("fooey (%s:%d)", FrameFile(fr), fr.Line), fmt.Sprintf("%+s", e))
+	require.Equal(t, "fooey", fmt.Sprintf("%s", e))
+	require.Equal(t, fmt.Sprintf("fooey (%s)", e.Location()), fmt.Sprintf("%+s", e.WithCause(errors.New("cause"))))
+
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	require.Equal(t, "fooey", fmt.Sprintf("%+s", New("fooey")))
+}
+
+func TestWrapW(t *testing.T) {
+	t.Run("with %w", func(t *testing.T) {
+		cause := errors.New("cause")
+		e := WrapW("reading %s: %w (attempt %d)", "file", cause, 2)
+		require.Equal(t, "reading file: cause (attempt 2)", e.Error())
+		require.Equal(t, "reading file: cause (attempt 2)", e.FullMessage())
+		require.Equal(t, "reading file: cause (attempt 2)", fmt.Sprintf("%v", e))
+		require.Equal(t, fmt.Errorf("reading %s: %w (attempt %d)", "file", cause, 2).Error(), e.Error())
+		require.Equal(t, cause, e.Unwrap())
+		require.True(t, errors.Is(e, cause))
+		require.NotEmpty(t, e.StackInfo())
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestWrapW.func1", e.StackInfo()[0].Function)
+
+		e2 := e.WithCause(errors.New("other"))
+		require.Equal(t, "reading file: cause (attempt 2): other", e2.FullMessage())
+	})
+	t.Run("stack error cause", func(t *testing.T) {
+		cause := New("cause")
+		e := WrapW("context: %w", cause)
+		require.Equal(t, "context: cause", e.Error())
+		require.Equal(t, cause, e.Cause())
+
+		// verbose retains the stack of the cause (but doesn't repeat its message)...
+		s := fmt.Sprintf("%+v", e)
+		require.True(t, strings.HasPrefix(s, "context: cause\nStack:\n"))
+		require.Equal(t, 1, strings.Count(s, "cause"))
+		fr := cause.StackInfo()[0]
+		require.Contains(t, s, fmt.Sprintf("\n\t%s:%d\n", fr.Function, fr.Line))
+		fr = e.StackInfo()[0]
+		require.Contains(t, s, fmt.Sprintf("\n\t%s:%d\n", fr.Function, fr.Line))
+		require.Equal(t, 2, strings.Count(s, "Stack:"))
+
+		// causes of the cause are not lost...
+		e = WrapW("context: %w", Wrap(errors.New("root"), "cause"))
+		s = fmt.Sprintf("%+v", e)
+		require.True(t, strings.HasPrefix(s, "context: cause: root\nStack:\n"))
+		require.Equal(t, 1, strings.Count(s, "root"))
+		require.Equal(t, 2, strings.Count(s, "Stack:"))
+	})
+	t.Run("without %w", func(t *testing.T) {
+		e := WrapW("fooey %d", 42)
+		require.Equal(t, "fooey 42", e.Error())
+		require.Equal(t, "fooey 42", fmt.Sprintf("%v", e))
+		require.NoError(t, e.Unwrap())
+		require.NotEmpty(t, e.StackInfo())
+	})
+	t.Run("multiple %w", func(t *testing.T) {
+		e := WrapW("%w and %w", errors.New("a"), errors.New("b"))
+		require.Equal(t, "a and b", e.Error())
+		require.NoError(t, e.Unwrap())
+	})
+}
+
+func TestError_MessageTemplate(t *testing.T) {
+	e := Newf("fooey %s %d", "bar", 42)
+	require.Equal(t, "fooey bar 42", e.Error())
+	format, args := e.MessageTemplate()
+	require.Equal(t, "fooey %s %d", format)
+	require.Nil(t, args)
+
+	CaptureMessageArgs = true
+	defer func() {
+		CaptureMessageArgs = false
+	}()
+	e = Newf("fooey %s %d", "bar", 42)
+	format, args = e.MessageTemplate()
+	require.Equal(t, "fooey %s %d", format)
+	require.Equal(t, []any{"bar", 42}, args)
+	format, args = e.WithCode("code").MessageTemplate()
+	require.Equal(t, "fooey %s %d", format)
+	require.Equal(t, []any{"bar", 42}, args)
+
+	format, args = New("fooey").MessageTemplate()
+	require.Equal(t, "fooey", format)
+	require.Nil(t, args)
+}
+
+func TestNewWithCause(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	cause := errors.New("cause")
+	for range 1 {
+		e1, e2 := NewWithCause("fooey", cause), New("fooey").WithCause(cause)
+		require.Equal(t, e2.Error(), e1.Error())
+		require.Equal(t, fmt.Sprintf("%v", e2), fmt.Sprintf("%v", e1))
+		require.Equal(t, fmt.Sprintf("%+v", e2), fmt.Sprintf("%+v", e1))
+		require.Equal(t, cause, e1.Unwrap())
+	}
+	e := NewWithCause("fooey", nil)
+	require.NotNil(t, e)
+	require.Equal(t, "fooey", fmt.Sprintf("%v", e))
+	require.NoError(t, e.Unwrap())
+}
+
+func TestNewfWithCause(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	cause := errors.New("cause")
+	for range 1 {
+		e1, e2 := NewfWithCause(cause, "fooey %d", 42), Newf("fooey %d", 42).WithCause(cause)
+		require.Equal(t, "fooey 42", e1.Error())
+		require.Equal(t, fmt.Sprintf("%v", e2), fmt.Sprintf("%v", e1))
+		require.Equal(t, fmt.Sprintf("%+v", e2), fmt.Sprintf("%+v", e1))
+		require.Equal(t, cause, e1.Unwrap())
+		format, _ := e1.MessageTemplate()
+		require.Equal(t, "fooey %d", format)
+	}
+	e := NewfWithCause(nil, "fooey %d", 42)
+	require.NotNil(t, e)
+	require.Equal(t, "fooey 42", fmt.Sprintf("%v", e))
+	require.NoError(t, e.Unwrap())
+}
+
+func TestCauseSeparator(t *testing.T) {
+	CauseSeparator = " -> "
+	DefaultPackageName = "stackerr"
+	defer func() {
+		CauseSeparator = ": "
+		DefaultPackageName = ""
+	}()
+	e := Wrap(Wrap(fmt.Errorf("plain: %w", New("root")), "middle"), "outer")
+	require.Equal(t, "outer -> middle -> plain: root", fmt.Sprintf("%v", e))
+	require.Equal(t, "outer -> middle -> plain: root", e.FullMessage())
+	require.True(t, strings.HasPrefix(fmt.Sprintf("%+v", e), "outer -> middle -> plain: root\nStack:"))
+	require.Equal(t, "outer -> a; b", New("outer").WithCauses(errors.New("a"), errors.New("b")).FullMessage())
+	require.Equal(t, "outer -> a; b", fmt.Sprintf("%v", New("outer").WithCauses(errors.New("a"), errors.New("b"))))
+
+	CauseSeparator = " caused by: "
+	require.Equal(t, "outer caused by: middle caused by: plain: root", fmt.Sprintf("%v", e))
+}
+
+func TestMaxCauseDepth(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := Wrap(Wrap(Wrap(Wrap(New("e5"), "e4"), "e3"), "e2"), "e1")
+	require.Equal(t, "e1: e2: e3: e4: e5", fmt.Sprintf("%+v", e))
+
+	MaxCauseDepth = 2
+	defer func() {
+		MaxCauseDepth = 0
+	}()
+	require.Equal(t, "e1: e2: e3: ... (2 more)", fmt.Sprintf("%+v", e))
+	require.Equal(t, "e1: e2: e3: e4: e5", fmt.Sprintf("%v", e))
+	require.Equal(t, "e1: e2: e3: e4: e5", e.FullMessage())
+	data, err := e.(*err).MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"e1","cause":{"message":"e2","cause":{"message":"e3","cause":"... (2 more)"}}}`, string(data))
+
+	MaxCauseDepth = 4
+	require.Equal(t, "e1: e2: e3: e4: e5", fmt.Sprintf("%+v", e))
+	MaxCauseDepth = 3
+	require.Equal(t, "e1: e2: e3: e4: ... (1 more)", fmt.Sprintf("%+v", e.WithCode("code")))
+}
+
+func TestPanicOnNewMatching(t *testing.T) {
+	PanicOnNewMatching = func(err StackError) bool {
+		return err.Error() == "boom"
+	}
+	defer func() {
+		PanicOnNewMatching = nil
+	}()
+	require.NotPanics(t, func() {
+		_ = New("fooey")
+	})
+	var recovered any
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		_ = Newf("%s", "boom")
+	}()
+	e, ok := recovered.(StackError)
+	require.True(t, ok)
+	require.Equal(t, "boom", e.Error())
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestPanicOnNewMatching.func4", e.StackInfo()[0].Function)
+
+	PanicOnNewMatching = nil
+	require.NotPanics(t, func() {
+		_ = New("boom")
+	})
+}
+
+func TestError_WithMessage(t *testing.T) {
+	cause := errors.New("cause")
+	e := New("fooey").WithCause(cause)
+	e2 := e.WithMessage("context")
+	require.Equal(t, "context: fooey", e2.Error())
+	require.Equal(t, "context: fooey: cause", e2.FullMessage())
+	require.Equal(t, "context: fooey: cause", fmt.Sprintf("%v", e2))
+	require.Equal(t, e.StackInfo(), e2.StackInfo())
+	require.Equal(t, cause, e2.Unwrap())
+	require.Equal(t, "fooey", e.Error())
+	require.Equal(t, "outer: context: fooey", e2.WithMessage("outer").Error())
+
+	ws := WithStack(cause).WithMessage("context")
+	require.Equal(t, "context: cause", ws.Error())
+	require.Equal(t, "context: cause", ws.FullMessage())
+	require.Equal(t, "context: cause", fmt.Sprintf("%v", ws))
+	require.ErrorIs(t, ws, cause)
+
+	// verbose retains the stack of a wrapped StackError...
+	inner := New("inner")
+	ws = WithStack(inner).WithMessage("context")
+	s := fmt.Sprintf("%+v", ws)
+	require.True(t, strings.HasPrefix(s, "context: inner\nStack:\n"))
+	require.Equal(t, 1, strings.Count(s, "inner"))
+	fr := inner.StackInfo()[0]
+	require.Contains(t, s, fmt.Sprintf("\n\t%s:%d\n", fr.Function, fr.Line))
+	require.Equal(t, 2, strings.Count(s, "Stack:"))
+}
+
+func TestError_Format_Quoted(t *testing.T) {
+	e := Wrap(errors.New("bad \"value\"\non line 2"), "parse failed")
+	require.Equal(t, `"parse failed"`, fmt.Sprintf("%q", e))
+	require.Equal(t, `"parse failed: bad \"value\"\non line 2"`, fmt.Sprintf("%+q", e))
+	require.Equal(t, `"fooey"`, fmt.Sprintf("%+q", New("fooey")))
+	require.Equal(t, `"héllo: wörld"`, fmt.Sprintf("%+q", Wrap(New("wörld"), "héllo")))
+	require.Equal(t, `"tab\there"`, fmt.Sprintf("%q", New("tab\there")))
+	require.NotContains(t, fmt.Sprintf("%+q", e), "Stack:")
+}
+
+func TestWithStack_ReplacedCause(t *testing.T) {
+	orig := errors.New("orig")
+	other := errors.New("other")
+
+	e := WithStack(orig).WithCause(nil)
+	require.Equal(t, "orig", e.Error())
+	require.Equal(t, "orig", e.FullMessage())
+	require.Equal(t, "orig", fmt.Sprintf("%v", e))
+	require.True(t, strings.HasPrefix(fmt.Sprintf("%+v", e), "orig\nStack:"))
+
+	e = WithStack(orig).WithCause(other)
+	require.Equal(t, "orig", e.Error())
+	require.Equal(t, "orig: other", e.FullMessage())
+	require.Equal(t, "orig: other", fmt.Sprintf("%v", e))
+
+	e = WithStack(orig).WithCauses(other, errors.New("another"))
+	require.Equal(t, "orig", e.Error())
+	require.Equal(t, "orig: other; another", e.FullMessage())
+	require.Equal(t, "orig: other; another", fmt.Sprintf("%v", e))
+
+	require.Equal(t, "", fullMessage(nil))
+	var sb strings.Builder
+	writeCause(&sb, nil, false, 0)
+	writeCause(&sb, nil, true, 0)
+	require.Equal(t, "", sb.String())
+}
+
+type sliceErr []string
+
+func (e sliceErr) Error() string {
+	return strings.Join(e, ",")
+}
+
+func TestError_WithSentinel_Uncomparable(t *testing.T) {
+	e := New("fooey").WithSentinel(sliceErr{"a"})
+	require.NotPanics(t, func() {
+		require.False(t, errors.Is(e, sliceErr{"a"}))
+	})
+	require.False(t, errors.Is(e, errors.New("a")))
+	require.False(t, e.(*err).Is(nil))
+	sentinel := errors.New("sentinel")
+	require.False(t, errors.Is(New("fooey").WithSentinel(sentinel), sliceErr{"a"}))
+	require.True(t, errors.Is(New("fooey").WithSentinel(sentinel), sentinel))
+	var target sliceErr
+	require.True(t, errors.As(e, &target))
+}
+
+func TestOnNew_SeesCompleteError(t *testing.T) {
+	var seen []string
+	sentinel := errors.New("s")
+	var joinIs bool
+	defer func() {
+		OnNew = nil
+	}()
+	OnNew = func(err StackError) {
+		if len(err.Unwraps()) > 0 {
+			joinIs = errors.Is(err, sentinel)
+		}
+		seen = append(seen, fmt.Sprintf("%s|%s", err.FullMessage(), err.Category()))
+	}
+	_ = NotFound("x")
+	_ = Join(sentinel, errors.New("b"))
+	_ = WrapW("ctx: %w", sentinel)
+	_ = WithStack(sentinel)
+	var m MultiError
+	m.Add(sentinel)
+	_ = m.ErrorOrNil()
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+		Must(0, sentinel)
+	}()
+	require.True(t, joinIs)
+	require.Equal(t, []string{
+		"x|not_found",
+		"s\nb|",
+		"ctx: s|",
+		"s|",
+		"s|",
+		"s|",
+	}, seen)
+}
+
+func TestPanicOnNewMatching_Category(t *testing.T) {
+	PanicOnNewMatching = func(err StackError) bool {
+		return err.Category() == CategoryNotFound
+	}
+	defer func() {
+		PanicOnNewMatching = nil
+	}()
+	require.Panics(t, func() {
+		_ = NotFound("x")
+	})
+	require.NotPanics(t, func() {
+		_ = Invalid("x")
+	})
+}
@@ -0,0 +1,52 @@
+package stackerr
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+func (e *err) WithField(key string, value any) StackError {
+	r := e.clone()
+	r.fields = make(map[string]any, len(e.fields)+1)
+	maps.Copy(r.fields, e.fields)
+	r.fields[key] = value
+	return r
+}
+
+func (e *err) WithFields(fields map[string]any) StackError {
+	r := e.clone()
+	r.fields = make(map[string]any, len(e.fields)+len(fields))
+	maps.Copy(r.fields, e.fields)
+	maps.Copy(r.fields, fields)
+	return r
+}
+
+func (e *err) Fields() map[string]any {
+	var inner map[string]any
+	if MergeCauseFields && e.cause != nil {
+		if fe, ok := e.cause.(interface{ Fields() map[string]any }); ok {
+			// cloned - as the map returned by a foreign error may be its own...
+			inner = maps.Clone(fe.Fields())
+		}
+	}
+	if len(inner) == 0 {
+		if len(e.fields) == 0 {
+			return nil
+		}
+		return maps.Clone(e.fields)
+	}
+	// outer fields override inner fields...
+	maps.Copy(inner, e.fields)
+	return inner
+}
+
+func (e *err) writeFields(w io.Writer) {
+	if len(e.fields) > 0 {
+		_, _ = io.WriteString(w, "\nFields:")
+		for _, k := range slices.Sorted(maps.Keys(e.fields)) {
+			_, _ = fmt.Fprintf(w, "\n\t%s: %v", k, e.fields[k])
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestError_WithField(t *testing.T) {
+	e := New("fooey")
+	require.Nil(t, e.Fields())
+	e2 := e.WithField("foo", 1)
+	require.Nil(t, e.Fields())
+	require.Equal(t, map[string]any{"foo": 1}, e2.Fields())
+	e3 := e2.WithField("foo", 2).WithField("bar", "baz")
+	require.Equal(t, map[string]any{"foo": 1}, e2.Fields())
+	require.Equal(t, map[string]any{"foo": 2, "bar": "baz"}, e3.Fields())
+
+	e3.Fields()["foo"] = 3
+	require.Equal(t, 2, e3.Fields()["foo"])
+}
+
+func TestError_WithFields(t *testing.T) {
+	e := New("fooey").WithField("foo", 1).WithField("bar", 2)
+	e2 := e.WithFields(map[string]any{"bar": 3, "baz": 4})
+	require.Equal(t, map[string]any{"foo": 1, "bar": 2}, e.Fields())
+	require.Equal(t, map[string]any{"foo": 1, "bar": 3, "baz": 4}, e2.Fields())
+
+	fields := map[string]any{"qux": 5}
+	e3 := e2.WithFields(fields)
+	fields["qux"] = 6
+	require.Equal(t, 5, e3.Fields()["qux"])
+	require.Nil(t, e.WithFields(nil).WithCause(nil).Fields()["qux"])
+}
+
+func TestError_FormatFields(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey").WithFields(map[string]any{"foo": 1, "bar": "baz"})
+	out := fmt.Sprintf("%+v", e)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 6)
+	require.Equal(t, "fooey", lines[0])
+	require.Equal(t, "Fields:", lines[1])
+	require.Equal(t, "\tbar: baz", lines[2])
+	require.Equal(t, "\tfoo: 1", lines[3])
+	require.Equal(t, "Stack:", lines[4])
+	require.Equal(t, "fooey", fmt.Sprintf("%v", e))
+}
+
+func TestError_MarshalJSON_Fields(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := New("fooey").WithField("foo", 1)
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey","fields":{"foo":1}}`, string(data))
+}
+
+func TestError_Fields_MergedOnWrap(t *testing.T) {
+	inner := New("inner").WithFields(map[string]any{"foo": 1, "bar": 2})
+	outer := Wrap(inner, "outer").WithFields(map[string]any{"bar": 3, "baz": 4})
+	require.Equal(t, map[string]any{"foo": 1, "bar": 3, "baz": 4}, outer.Fields())
+	require.Equal(t, map[string]any{"foo": 1, "bar": 2}, inner.Fields())
+
+	// disjoint...
+	outer = Wrap(inner, "outer").WithField("qux", 5)
+	require.Equal(t, map[string]any{"foo": 1, "bar": 2, "qux": 5}, outer.Fields())
+
+	// outer without fields...
+	outer = Wrap(inner, "outer")
+	require.Equal(t, map[string]any{"foo": 1, "bar": 2}, outer.Fields())
+	outer.Fields()["foo"] = 6
+	require.Equal(t, 1, inner.Fields()["foo"])
+
+	// multiple levels...
+	outer = Wrap(Wrap(inner, "middle").WithField("foo", 7), "outer").WithField("baz", 8)
+	require.Equal(t, map[string]any{"foo": 7, "bar": 2, "baz": 8}, outer.Fields())
+
+	// non-StackError cause...
+	require.Nil(t, Wrap(errors.New("inner"), "outer").Fields())
+}
+
+func TestError_Fields_MergeDisabled(t *testing.T) {
+	MergeCauseFields = false
+	defer func() {
+		MergeCauseFields = true
+	}()
+	inner := New("inner").WithField("foo", 1)
+	require.Nil(t, Wrap(inner, "outer").Fields())
+	require.Equal(t, map[string]any{"bar": 2}, Wrap(inner, "outer").WithField("bar", 2).Fields())
+}
+
+type foreignFieldsError struct {
+	fields map[string]any
+}
+
+func (e *foreignFieldsError) Error() string {
+	return "foreign"
+}
+
+func (e *foreignFieldsError) Fields() map[string]any {
+	return e.fields
+}
+
+func TestError_Fields_MergeDoesNotModifyCause(t *testing.T) {
+	f := &foreignFieldsError{fields: map[string]any{"a": 1}}
+	e := Wrap(f, "outer").WithField("b", 2)
+	require.Equal(t, map[string]any{"a": 1, "b": 2}, e.Fields())
+	require.Equal(t, map[string]any{"a": 1}, f.fields)
+}
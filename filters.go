@@ -0,0 +1,114 @@
+package stackerr
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PrefixFilter returns a PackageFilter that includes any package whose full path starts with the specified prefix
+func PrefixFilter(prefix string) PackageFilter {
+	return &prefixFilter{
+		prefix: prefix,
+	}
+}
+
+// AllFilters returns a PackageFilter that includes a package only if all the supplied filters include it
+func AllFilters(filters ...PackageFilter) PackageFilter {
+	return &allFilter{
+		filters: filters,
+	}
+}
+
+// AnyFilter returns a PackageFilter that includes a package if any of the supplied filters include it
+func AnyFilter(filters ...PackageFilter) PackageFilter {
+	return &anyFilter{
+		filters: filters,
+	}
+}
+
+// NotFilter returns a PackageFilter that includes a package only if the supplied filter does not include it
+func NotFilter(f PackageFilter) PackageFilter {
+	return &notFilter{
+		filter: f,
+	}
+}
+
+type prefixFilter struct {
+	prefix string
+}
+
+var _ PackageFilter = (*prefixFilter)(nil)
+
+func (pf *prefixFilter) Include(packageName string) bool {
+	return strings.HasPrefix(packageName, pf.prefix)
+}
+
+type allFilter struct {
+	filters []PackageFilter
+}
+
+var _ PackageFilter = (*allFilter)(nil)
+
+func (af *allFilter) Include(packageName string) bool {
+	for _, f := range af.filters {
+		if f != nil && !f.Include(packageName) {
+			return false
+		}
+	}
+	return true
+}
+
+type anyFilter struct {
+	filters []PackageFilter
+}
+
+var _ PackageFilter = (*anyFilter)(nil)
+
+func (af *anyFilter) Include(packageName string) bool {
+	for _, f := range af.filters {
+		if f != nil && f.Include(packageName) {
+			return true
+		}
+	}
+	return false
+}
+
+type notFilter struct {
+	filter PackageFilter
+}
+
+var _ PackageFilter = (*notFilter)(nil)
+
+func (nf *notFilter) Include(packageName string) bool {
+	return nf.filter == nil || !nf.filter.Include(packageName)
+}
+
+var (
+	namedFiltersMu sync.RWMutex
+	namedFilters   = map[string]PackageFilter{}
+)
+
+// RegisterFilter registers a named PackageFilter (replacing any filter previously registered with the same name) - so that
+// the DefaultPackageFilter can be switched by name at runtime (see UseFilter)
+//
+// Registering a nil filter is permitted (i.e. using it sets no package filter)
+func RegisterFilter(name string, f PackageFilter) {
+	namedFiltersMu.Lock()
+	defer namedFiltersMu.Unlock()
+	namedFilters[name] = f
+}
+
+// UseFilter sets the DefaultPackageFilter to the named filter (see RegisterFilter) - safe for concurrent use
+//
+// returns an error if no filter has been registered with the name (and DefaultPackageFilter is left unchanged)
+func UseFilter(name string) error {
+	namedFiltersMu.RLock()
+	f, ok := namedFilters[name]
+	namedFiltersMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown package filter %q", name)
+	}
+	SetPackageFilter(f)
+	return nil
+}
@@ -0,0 +1,105 @@
+package stackerr
+
+import (
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestAllFilters(t *testing.T) {
+	f := AllFilters(
+		&regexpFilter{rx: regexp.MustCompile(`^github\.com/myorg/`)},
+		NotFilter(&regexpFilter{rx: regexp.MustCompile(`/mocks(/|$)`)}),
+	)
+	require.True(t, f.Include("github.com/myorg/app"))
+	require.True(t, f.Include("github.com/myorg/app/handlers"))
+	require.False(t, f.Include("github.com/myorg/app/mocks"))
+	require.False(t, f.Include("github.com/myorg/app/mocks/sub"))
+	require.False(t, f.Include("github.com/other/app"))
+	require.False(t, f.Include("testing"))
+
+	require.True(t, AllFilters().Include("anything"))
+}
+
+func TestAnyFilter(t *testing.T) {
+	f := AnyFilter(
+		&packageFilter{packageName: "github.com/myorg/app"},
+		&packageFilter{packageName: "github.com/myorg/lib"},
+	)
+	require.True(t, f.Include("github.com/myorg/app"))
+	require.True(t, f.Include("github.com/myorg/lib"))
+	require.False(t, f.Include("github.com/myorg/other"))
+
+	require.False(t, AnyFilter().Include("anything"))
+}
+
+func TestNotFilter(t *testing.T) {
+	f := NotFilter(&packageFilter{packageName: "testing"})
+	require.False(t, f.Include("testing"))
+	require.True(t, f.Include("github.com/myorg/app"))
+
+	require.True(t, NotFilter(nil).Include("anything"))
+}
+
+func TestFilters_Capture(t *testing.T) {
+	DefaultPackageFilter = AllFilters(
+		&regexpFilter{rx: regexp.MustCompile(`^github\.com/go-andiamo/`)},
+		NotFilter(&packageFilter{packageName: "testing"}),
+	)
+	defer func() {
+		DefaultPackageFilter = nil
+	}()
+	si := New("fooey").StackInfo()
+	require.Len(t, si, 1)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestFilters_Capture", si[0].Function)
+}
+
+func TestPrefixFilter(t *testing.T) {
+	f := PrefixFilter("github.com/myorg/app")
+	require.True(t, f.Include("github.com/myorg/app"))
+	require.True(t, f.Include("github.com/myorg/app/handlers"))
+	require.True(t, f.Include("github.com/myorg/app/internal/db"))
+	require.False(t, f.Include("github.com/myorg/lib"))
+	require.False(t, f.Include("github.com/other/app"))
+	require.False(t, f.Include("testing"))
+}
+
+func TestSetDefaultPackageFilterPrefix(t *testing.T) {
+	SetDefaultPackageFilterPrefix("github.com/go-andiamo/")
+	defer func() {
+		DefaultPackageFilter = nil
+	}()
+	si := New("fooey").StackInfo()
+	require.Len(t, si, 1)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestSetDefaultPackageFilterPrefix", si[0].Function)
+}
+
+func TestRegisterFilter_UseFilter(t *testing.T) {
+	defer func() {
+		DefaultPackageFilter = nil
+		namedFilters = map[string]PackageFilter{}
+	}()
+	RegisterFilter("testing", PrefixFilter("testing"))
+	RegisterFilter("self", PrefixFilter("github.com/go-andiamo/stackerr"))
+	RegisterFilter("none", nil)
+
+	require.NoError(t, UseFilter("testing"))
+	si := New("fooey").StackInfo()
+	require.NotEmpty(t, si)
+	require.False(t, si.Contains("stackerr."))
+	require.True(t, si.Contains("testing.tRunner"))
+
+	require.NoError(t, UseFilter("self"))
+	si = New("fooey").StackInfo()
+	require.NotEmpty(t, si)
+	require.True(t, si.Contains("stackerr.TestRegisterFilter_UseFilter"))
+	require.False(t, si.Contains("testing.tRunner"))
+
+	err := UseFilter("unknown")
+	require.Error(t, err)
+	require.Equal(t, `unknown package filter "unknown"`, err.Error())
+	require.Equal(t, namedFilters["self"], DefaultPackageFilterValue())
+
+	require.NoError(t, UseFilter("none"))
+	require.Nil(t, DefaultPackageFilterValue())
+}
@@ -0,0 +1,28 @@
+package stackerr
+
+import (
+	"hash/fnv"
+	"io"
+	"strconv"
+)
+
+func (e *err) Fingerprint() string {
+	h := fnv.New64a()
+	if e.key != "" {
+		_, _ = io.WriteString(h, e.key)
+	} else if e.format != "" {
+		_, _ = io.WriteString(h, e.format)
+	} else {
+		_, _ = io.WriteString(h, e.message)
+	}
+	_, _ = io.WriteString(h, "\x00")
+	_, _ = io.WriteString(h, e.code)
+	si := e.StackInfo()
+	for i := 0; i < FingerprintDepth && i < len(si); i++ {
+		_, _ = io.WriteString(h, "\x00")
+		_, _ = io.WriteString(h, si[i].Function)
+		_, _ = io.WriteString(h, ":")
+		_, _ = io.WriteString(h, strconv.Itoa(si[i].Line))
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
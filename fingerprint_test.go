@@ -0,0 +1,81 @@
+package stackerr
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestError_Fingerprint(t *testing.T) {
+	errs := make([]StackError, 0, 2)
+	for i := range 2 {
+		errs = append(errs, Newf("fooey %d", i))
+	}
+	require.NotEqual(t, errs[0].Error(), errs[1].Error())
+	require.NotEmpty(t, errs[0].Fingerprint())
+	require.Equal(t, errs[0].Fingerprint(), errs[1].Fingerprint())
+	require.Equal(t, errs[0].Fingerprint(), errs[0].Fingerprint())
+	require.NotEqual(t, errs[0].Fingerprint(), errs[0].WithCode("code").Fingerprint())
+	// same format from different site...
+	require.NotEqual(t, errs[0].Fingerprint(), Newf("fooey %d", 0).Fingerprint())
+
+	news := make([]StackError, 0, 2)
+	for _, msg := range []string{"fooey", "other"} {
+		news = append(news, New(msg))
+	}
+	require.NotEqual(t, news[0].Fingerprint(), news[1].Fingerprint())
+}
+
+func TestFingerprintDepth(t *testing.T) {
+	defer func() {
+		FingerprintDepth = 3
+	}()
+	e1 := New("fooey")
+	e2 := New("fooey")
+	require.NotEqual(t, e1.Fingerprint(), e2.Fingerprint())
+	FingerprintDepth = 0
+	require.Equal(t, e1.Fingerprint(), e2.Fingerprint())
+}
+
+func TestOnErrorMetric(t *testing.T) {
+	counts := map[string]int{}
+	OnErrorMetric = func(fingerprint string, err StackError) {
+		require.Equal(t, err.Fingerprint(), fingerprint)
+		counts[fingerprint]++
+	}
+	defer func() {
+		OnErrorMetric = nil
+	}()
+	site1 := func(id int) StackError {
+		return Newf("user %d not found", id)
+	}
+	site2 := func() StackError {
+		return New("fooey")
+	}
+	var fp1, fp2 string
+	for i := range 5 {
+		fp1 = site1(i).Fingerprint()
+	}
+	for range 3 {
+		fp2 = site2().Fingerprint()
+	}
+	require.NotEqual(t, fp1, fp2)
+	require.Equal(t, map[string]int{fp1: 5, fp2: 3}, counts)
+
+	// keyed and redacted errors are fingerprinted by their key/format...
+	clear(counts)
+	for i := range 2 {
+		_ = NewKeyed("errors.notFound", fmt.Sprintf("not found %d", i))
+		_ = NewfRedacted("secret %s", strconv.Itoa(i))
+	}
+	require.Len(t, counts, 2)
+	for _, c := range counts {
+		require.Equal(t, 2, c)
+	}
+}
+
+func TestOnErrorMetric_Nil(t *testing.T) {
+	require.Nil(t, OnErrorMetric)
+	require.NotEmpty(t, New("fooey").Fingerprint())
+}
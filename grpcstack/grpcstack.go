@@ -0,0 +1,68 @@
+// Package grpcstack provides gRPC status code integration for stackerr
+//
+// This is a separate module (github.com/go-andiamo/stackerr/grpcstack) so that the core stackerr module does not depend on gRPC
+package grpcstack
+
+import (
+	"errors"
+	"github.com/go-andiamo/stackerr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxChainLength is the maximum number of errors traversed when walking an Unwrap chain
+// (guarding against self-referencing chains)
+const maxChainLength = 1000
+
+type codeKey struct{}
+
+// WithGRPCCode returns a StackError with the gRPC status code set
+func WithGRPCCode(err stackerr.StackError, code codes.Code) stackerr.StackError {
+	return err.WithValue(codeKey{}, code)
+}
+
+// GRPCCode returns the gRPC status code of the StackError (or codes.Unknown if no code has been set)
+func GRPCCode(err stackerr.StackError) codes.Code {
+	if code, ok := explicitCode(err); ok {
+		return code
+	}
+	return codes.Unknown
+}
+
+// GRPCStatusOf walks the Unwrap chain of the error and returns the first explicit gRPC status code found (see WithGRPCCode) - or
+// the code of the first error that has a gRPC status (i.e. implements GRPCStatus() *status.Status)
+//
+// If no code is found, codes.Unknown is returned (if err is nil, codes.OK is returned)
+func GRPCStatusOf(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	for i := 0; err != nil && i < maxChainLength; i++ {
+		if code, ok := explicitCode(err); ok {
+			return code
+		} else if s, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+			return s.GRPCStatus().Code()
+		}
+		err = errors.Unwrap(err)
+	}
+	return codes.Unknown
+}
+
+// Status converts the error into a *status.Status - with the code determined by GRPCStatusOf and the message of the error
+//
+// Returns nil if err is nil
+func Status(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	return status.New(GRPCStatusOf(err), err.Error())
+}
+
+func explicitCode(err error) (codes.Code, bool) {
+	if v, ok := err.(interface{ Value(key any) any }); ok {
+		if code, ok := v.Value(codeKey{}).(codes.Code); ok {
+			return code, true
+		}
+	}
+	return codes.Unknown, false
+}
@@ -0,0 +1,42 @@
+package grpcstack
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-andiamo/stackerr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestWithGRPCCode(t *testing.T) {
+	e := stackerr.New("fooey")
+	require.Equal(t, codes.Unknown, GRPCCode(e))
+	e2 := WithGRPCCode(e, codes.NotFound)
+	require.Equal(t, codes.NotFound, GRPCCode(e2))
+	require.Equal(t, codes.Unknown, GRPCCode(e))
+	require.Equal(t, codes.NotFound, GRPCCode(e2.WithCode("code")))
+	require.Equal(t, "fooey", e2.Error())
+}
+
+func TestGRPCStatusOf(t *testing.T) {
+	require.Equal(t, codes.OK, GRPCStatusOf(nil))
+	require.Equal(t, codes.Unknown, GRPCStatusOf(errors.New("fooey")))
+	require.Equal(t, codes.Unknown, GRPCStatusOf(stackerr.New("fooey")))
+	e := stackerr.Wrap(stackerr.Wrap(WithGRPCCode(stackerr.New("inner"), codes.NotFound), "middle"), "outer")
+	require.Equal(t, codes.NotFound, GRPCStatusOf(e))
+	require.Equal(t, codes.NotFound, GRPCStatusOf(fmt.Errorf("plain: %w", e)))
+	require.Equal(t, codes.Internal, GRPCStatusOf(WithGRPCCode(stackerr.Wrap(e, "outermost"), codes.Internal)))
+	require.Equal(t, codes.PermissionDenied, GRPCStatusOf(stackerr.Wrap(status.Error(codes.PermissionDenied, "denied"), "outer")))
+}
+
+func TestStatus(t *testing.T) {
+	require.Nil(t, Status(nil))
+	s := Status(WithGRPCCode(stackerr.New("fooey"), codes.InvalidArgument))
+	require.Equal(t, codes.InvalidArgument, s.Code())
+	require.Equal(t, "fooey", s.Message())
+	s = Status(errors.New("plain"))
+	require.Equal(t, codes.Unknown, s.Code())
+	require.Equal(t, "plain", s.Message())
+}
@@ -0,0 +1,71 @@
+// Package httpstack provides net/http error response integration for stackerr
+//
+// This is a separate package so that HTTP handler concerns (response bodies, logging) are kept out of the core stackerr package
+package httpstack
+
+import (
+	"encoding/json"
+	"github.com/go-andiamo/stackerr"
+	"log"
+	"net/http"
+)
+
+// Response is the JSON body written by Handle
+type Response struct {
+	Message string         `json:"message"`
+	Code    string         `json:"code,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// GenericMessage is the message written by Handle when the error has no StackError in its chain
+var GenericMessage = http.StatusText(http.StatusInternalServerError)
+
+// Logger is called by Handle to log the error server-side (the error is never logged when nil)
+//
+// The default logs the error (with stack info) using the standard logger - i.e. as formatted with %+v
+var Logger = func(err error) {
+	log.Printf("%+v", err)
+}
+
+// Handle writes a JSON error response (see Response) for the error - and logs the error server-side (see Logger)
+//
+// The status is determined by stackerr.HTTPStatusOf. If there is a StackError in the chain of the error, the message is
+// that of the error and the code (see stackerr.CodeOf) and fields of the StackError are included - otherwise, the status
+// is http.StatusInternalServerError and the message is GenericMessage. The stack info is never written to the response.
+//
+// If err is nil, nothing is written
+func Handle(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+	if Logger != nil {
+		Logger(err)
+	}
+	status := stackerr.HTTPStatusOf(err)
+	body := Response{Message: GenericMessage}
+	if se, ok := stackerr.AsStackError(err); ok {
+		body.Message = err.Error()
+		body.Code, _ = stackerr.CodeOf(err)
+		body.Fields = se.Fields()
+	} else {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// HandlerFunc is an http.Handler whose function returns an error - any error returned is written using Handle
+//
+// e.g.
+//
+//	mux.Handle("/users", httpstack.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		return stackerr.New("not found").WithHTTPStatus(http.StatusNotFound)
+//	}))
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+var _ http.Handler = HandlerFunc(nil)
+
+func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	Handle(w, fn(w, r))
+}
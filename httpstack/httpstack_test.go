@@ -0,0 +1,143 @@
+package httpstack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-andiamo/stackerr"
+	"github.com/stretchr/testify/require"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func captureLogs(t *testing.T) *[]error {
+	logged := make([]error, 0)
+	before := Logger
+	Logger = func(err error) {
+		logged = append(logged, err)
+	}
+	t.Cleanup(func() {
+		Logger = before
+	})
+	return &logged
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) Response {
+	var r Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+	return r
+}
+
+func TestHandle(t *testing.T) {
+	logged := captureLogs(t)
+	err := stackerr.New("user not found").
+		WithHTTPStatus(http.StatusNotFound).
+		WithCode("NOT_FOUND").
+		WithField("id", "123")
+	rec := httptest.NewRecorder()
+	Handle(rec, err)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, Response{Message: "user not found", Code: "NOT_FOUND", Fields: map[string]any{"id": "123"}}, decode(t, rec))
+	require.Equal(t, `{"message":"user not found","code":"NOT_FOUND","fields":{"id":"123"}}`+"\n", rec.Body.String())
+	require.NotContains(t, rec.Body.String(), "stack")
+	require.NotContains(t, rec.Body.String(), "httpstack_test.go")
+	require.Equal(t, []error{err}, *logged)
+}
+
+func TestHandle_Wrapped(t *testing.T) {
+	_ = captureLogs(t)
+	err := fmt.Errorf("handler: %w", stackerr.Wrap(
+		stackerr.New("inner").WithHTTPStatus(http.StatusConflict).WithCode("CONFLICT").WithField("foo", 1), "outer"))
+	rec := httptest.NewRecorder()
+	Handle(rec, err)
+	require.Equal(t, http.StatusConflict, rec.Code)
+	require.Equal(t, `{"message":"handler: outer: inner","code":"CONFLICT","fields":{"foo":1}}`+"\n", rec.Body.String())
+}
+
+func TestHandle_DefaultStatus(t *testing.T) {
+	_ = captureLogs(t)
+	rec := httptest.NewRecorder()
+	Handle(rec, stackerr.New("fooey"))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, `{"message":"fooey"}`+"\n", rec.Body.String())
+}
+
+func TestHandle_Category(t *testing.T) {
+	_ = captureLogs(t)
+	rec := httptest.NewRecorder()
+	Handle(rec, stackerr.NotFound("user not found"))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, `{"message":"user not found"}`+"\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	Handle(rec, stackerr.Wrap(stackerr.Forbidden("not allowed"), "access"))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestHandle_NotStackError(t *testing.T) {
+	logged := captureLogs(t)
+	err := errors.New("pq: password authentication failed for user \"admin\"")
+	rec := httptest.NewRecorder()
+	Handle(rec, err)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, Response{Message: "Internal Server Error"}, decode(t, rec))
+	require.NotContains(t, rec.Body.String(), "admin")
+	require.Equal(t, []error{err}, *logged)
+}
+
+func TestHandle_Nil(t *testing.T) {
+	logged := captureLogs(t)
+	rec := httptest.NewRecorder()
+	Handle(rec, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Empty(t, *logged)
+}
+
+func TestHandle_DefaultLogger(t *testing.T) {
+	var sb strings.Builder
+	log.SetOutput(&sb)
+	defer log.SetOutput(os.Stderr)
+	rec := httptest.NewRecorder()
+	Handle(rec, stackerr.New("fooey"))
+	// the stack is logged server-side - but not written to the response...
+	require.Contains(t, sb.String(), "fooey\nStack:")
+	require.Contains(t, sb.String(), "httpstack.TestHandle_DefaultLogger")
+	require.NotContains(t, rec.Body.String(), "TestHandle_DefaultLogger")
+
+	before := Logger
+	Logger = nil
+	defer func() {
+		Logger = before
+	}()
+	sb.Reset()
+	rec = httptest.NewRecorder()
+	Handle(rec, stackerr.New("fooey"))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Empty(t, sb.String())
+}
+
+func TestHandlerFunc(t *testing.T) {
+	_ = captureLogs(t)
+	h := HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.URL.Path == "/ok" {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return stackerr.New("not found").WithHTTPStatus(http.StatusNotFound)
+	})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, `{"message":"not found"}`+"\n", rec.Body.String())
+}
@@ -0,0 +1,61 @@
+package stackerr
+
+import "strings"
+
+// Join creates a new StackError, with stack info, that combines the non-nil errors
+//
+// The message of the returned error is the messages of each non-nil error, separated by newlines (as with errors.Join)
+// and errors.Is / errors.As will match against any of the joined errors
+//
+// Join returns nil if all errors are nil
+func Join(errs ...error) StackError {
+	joined := make([]error, 0, len(errs))
+	for _, e := range errs {
+		if e != nil {
+			joined = append(joined, e)
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	e := allocError(joinMessages(joined), getStackInfo(1, newOptions()), nil)
+	e.causes = joined
+	e.joined = true
+	return e.created()
+}
+
+func joinMessages(errs []error) string {
+	var sb strings.Builder
+	for i, e := range errs {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(e.Error())
+	}
+	return sb.String()
+}
+
+// WrapAll creates a new StackError, with stack info, wrapping the non-nil errors (e.g. as collected from concurrent workers)
+//
+// If there is exactly one non-nil error, it is wrapped as the cause (as with Wrap) - if there are several, they are
+// set as the multiple causes (see StackError.WithCauses).
+// The stack info is based on the point at which WrapAll is called.
+//
+// WrapAll returns nil if all errors are nil
+func WrapAll(msg string, errs []error) StackError {
+	causes := make([]error, 0, len(errs))
+	for _, e := range errs {
+		if e != nil {
+			causes = append(causes, e)
+		}
+	}
+	switch len(causes) {
+	case 0:
+		return nil
+	case 1:
+		return newError(msg, getStackInfo(1, newOptions()), causes[0])
+	}
+	e := allocError(msg, getStackInfo(1, newOptions()), nil)
+	e.causes = causes
+	return e.created()
+}
@@ -0,0 +1,127 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	sentinel1 := errors.New("sentinel 1")
+	sentinel2 := New("sentinel 2")
+	e := Join(nil, sentinel1, nil, Wrap(sentinel2, "wrapped"))
+	require.Error(t, e)
+	require.Equal(t, "sentinel 1\nwrapped", e.Error())
+	require.Equal(t, errors.Join(sentinel1, Wrap(sentinel2, "wrapped")).Error(), e.Error())
+	require.Len(t, e.Unwraps(), 2)
+	require.NoError(t, e.Unwrap())
+	require.True(t, errors.Is(e, sentinel1))
+	require.True(t, errors.Is(e, sentinel2))
+	require.False(t, errors.Is(e, errors.New("sentinel 1")))
+
+	si := e.StackInfo()
+	require.Len(t, si, 1)
+	require.True(t, strings.HasSuffix(si[0].Function, ".TestJoin"))
+	require.Equal(t, 19, si[0].Line)
+
+	var target *testSentinel
+	require.False(t, errors.As(e, &target))
+	e = Join(errors.New("fooey"), &testSentinel{code: 1})
+	require.True(t, errors.As(e, &target))
+	require.Equal(t, 1, target.code)
+}
+
+func TestJoin_AllNil(t *testing.T) {
+	require.NoError(t, Join())
+	require.NoError(t, Join(nil, nil))
+}
+
+func TestError_WithCauses(t *testing.T) {
+	cause1 := errors.New("cause 1")
+	cause2 := New("cause 2")
+	cause3 := fmt.Errorf("cause 3: %w", io.EOF)
+	e := New("closing").WithCauses(cause1, nil, cause2, cause3)
+	require.Equal(t, "closing", e.Error())
+	require.Equal(t, cause1, e.Cause())
+	require.NoError(t, e.Unwrap())
+	require.Equal(t, []error{cause1, cause2, cause3}, e.Unwraps())
+	require.True(t, errors.Is(e, cause1))
+	require.True(t, errors.Is(e, cause2))
+	require.True(t, errors.Is(e, cause3))
+	require.True(t, errors.Is(e, io.EOF))
+	require.False(t, errors.Is(e, errors.New("cause 1")))
+	require.True(t, errors.Is(Wrap(e, "wrapped"), io.EOF))
+	require.Equal(t, "closing: cause 1; cause 2; cause 3: EOF", fmt.Sprintf("%v", e))
+	require.Equal(t, "closing: cause 1; cause 2; cause 3: EOF", e.FullMessage())
+
+	t.Run("+v", func(t *testing.T) {
+		DefaultFrameFormatter = nil
+		defer func() {
+			DefaultFrameFormatter = &frameFormatter{}
+		}()
+		require.Equal(t, "closing\nCauses:\n\t1. cause 1\n\t2. cause 2\n\t3. cause 3: EOF", fmt.Sprintf("%+v", e))
+	})
+	t.Run("replaces cause", func(t *testing.T) {
+		e := New("fooey").WithCause(cause1).WithCauses(cause2, cause3)
+		require.Equal(t, cause2, e.Cause())
+		require.False(t, errors.Is(e, cause1))
+		e = e.WithCause(cause1)
+		require.Equal(t, cause1, e.Cause())
+		require.Equal(t, []error{cause1}, e.Unwraps())
+		require.False(t, errors.Is(e, cause2))
+	})
+	t.Run("no causes", func(t *testing.T) {
+		e := New("fooey").WithCauses(nil, nil)
+		require.NoError(t, e.Cause())
+		require.Nil(t, e.Unwraps())
+		require.Equal(t, "fooey", fmt.Sprintf("%v", e))
+	})
+}
+
+func TestWrapAll(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		require.Nil(t, WrapAll("workers failed", nil))
+		require.Nil(t, WrapAll("workers failed", []error{}))
+		require.Nil(t, WrapAll("workers failed", []error{nil, nil}))
+	})
+	t.Run("one", func(t *testing.T) {
+		cause := errors.New("worker 2 failed")
+		var line int
+		e := WrapAll("workers failed", []error{nil, causeAt(cause, &line), nil})
+		require.Equal(t, "workers failed", e.Error())
+		require.Equal(t, cause, e.Cause())
+		require.Equal(t, cause, e.Unwrap())
+		require.True(t, errors.Is(e, cause))
+		require.Equal(t, "workers failed: worker 2 failed", fmt.Sprintf("%v", e))
+		fr, ok := e.CallerFrame()
+		require.True(t, ok)
+		require.True(t, strings.HasSuffix(fr.Function, ".TestWrapAll.func2"))
+		require.Equal(t, line, fr.Line)
+	})
+	t.Run("many", func(t *testing.T) {
+		cause1 := errors.New("worker 1 failed")
+		cause2 := New("worker 3 failed")
+		errs := []error{cause1, nil, cause2}
+		var line int
+		e := WrapAll("workers failed", errs)
+		lineNo(&line)
+		require.Equal(t, "workers failed", e.Error())
+		require.Equal(t, []error{cause1, cause2}, e.Unwraps())
+		require.NoError(t, e.Unwrap())
+		require.True(t, errors.Is(e, cause1))
+		require.True(t, errors.Is(e, cause2))
+		require.Equal(t, "workers failed: worker 1 failed; worker 3 failed", fmt.Sprintf("%v", e))
+		fr, _ := e.CallerFrame()
+		require.Equal(t, line-1, fr.Line)
+		// the slice supplied is not retained...
+		errs[0] = errors.New("other")
+		require.Equal(t, cause1, e.Cause())
+	})
+}
@@ -0,0 +1,167 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+)
+
+var _ json.Marshaler = (*err)(nil)
+
+// MarshalJSON implements json.Marshaler
+//
+// The error is marshalled as an object with "message", optional "cause" and "stack" properties
+//
+// Note: if DefaultFrameFormatter is nil, the stack is omitted (as with formatting using %+v)
+func (e *err) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.jsonObject(0))
+}
+
+type jsonError struct {
+	Message   string         `json:"message"`
+	Code      string         `json:"code,omitempty"`
+	TraceID   string         `json:"trace_id,omitempty"`
+	Severity  Severity       `json:"severity,omitempty"`
+	Category  Category       `json:"category,omitempty"`
+	Cause     any            `json:"cause,omitempty"`
+	Causes    []any          `json:"causes,omitempty"`
+	Fields    map[string]any `json:"fields,omitempty"`
+	Goroutine uint64         `json:"goroutine,omitempty"`
+	Time      time.Time      `json:"time,omitzero"`
+	Stack     []jsonFrame    `json:"stack,omitempty"`
+}
+
+type jsonFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// jsonObject returns the JSON representation of the error - the depth is the number of cause levels already descended (see MaxCauseDepth)
+func (e *err) jsonObject(depth int) jsonError {
+	result := jsonError{
+		Message:   e.text(),
+		Code:      e.code,
+		TraceID:   e.traceID,
+		Severity:  e.severity,
+		Category:  e.category,
+		Fields:    e.fields,
+		Goroutine: e.goroutine,
+		Time:      e.timestamp,
+	}
+	if e.cause != nil {
+		if MaxCauseDepth > 0 && depth >= MaxCauseDepth {
+			result.Cause = fmt.Sprintf("... (%d more)", len(Causes(e.cause)))
+		} else {
+			result.Cause = jsonCause(e.cause, depth+1)
+		}
+	}
+	if len(e.causes) > 0 {
+		result.Causes = make([]any, 0, len(e.causes))
+		for _, c := range e.causes {
+			result.Causes = append(result.Causes, jsonCause(c, depth+1))
+		}
+	}
+	si := e.StackInfo()
+	if FormatFrameFilter != nil {
+		si, _ = filterStack(si, nil, FormatFrameFilter)
+	}
+	if len(si) > 0 && DefaultFrameFormatterValue() != nil {
+		if StackOrderOuterFirst {
+			si, _ = reverseStack(si, nil)
+		}
+		result.Stack = make([]jsonFrame, 0, len(si))
+		for _, fr := range si {
+			result.Stack = append(result.Stack, jsonFrame{
+				Function: fr.Function,
+				File:     FrameFile(fr),
+				Line:     fr.Line,
+			})
+		}
+	}
+	return result
+}
+
+func jsonCause(cause error, depth int) any {
+	switch ct := cause.(type) {
+	case *err:
+		return ct.jsonObject(depth)
+	case StackError:
+		if m, ok := ct.(json.Marshaler); ok {
+			return m
+		}
+	}
+	return cause.Error()
+}
+
+// ToMap returns a map representation of the error - with "message" and (when set) "code", "trace_id", "severity", "category",
+// "fields", "goroutine", "time", "cause" (a map if the cause is a StackError, otherwise the cause message), "causes"
+// and "stack" (a slice of maps, each with "function", "file" and "line")
+//
+// This is lower level than MarshalJSON - allowing adapters to reshape the output before serializing. The map is built from
+// the same representation as MarshalJSON - so the same settings apply (e.g. the stack is omitted if DefaultFrameFormatter is nil).
+// Note: as with any map, iteration order is not deterministic
+func (e *err) ToMap() map[string]any {
+	return e.jsonObject(0).toMap()
+}
+
+func (je jsonError) toMap() map[string]any {
+	result := map[string]any{
+		"message": je.Message,
+	}
+	if je.Code != "" {
+		result["code"] = je.Code
+	}
+	if je.TraceID != "" {
+		result["trace_id"] = je.TraceID
+	}
+	if je.Severity != "" {
+		result["severity"] = je.Severity
+	}
+	if je.Category != "" {
+		result["category"] = je.Category
+	}
+	if len(je.Fields) > 0 {
+		result["fields"] = maps.Clone(je.Fields)
+	}
+	if je.Goroutine != 0 {
+		result["goroutine"] = je.Goroutine
+	}
+	if !je.Time.IsZero() {
+		result["time"] = je.Time
+	}
+	if je.Cause != nil {
+		result["cause"] = mapCause(je.Cause)
+	}
+	if len(je.Causes) > 0 {
+		causes := make([]any, 0, len(je.Causes))
+		for _, c := range je.Causes {
+			causes = append(causes, mapCause(c))
+		}
+		result["causes"] = causes
+	}
+	if len(je.Stack) > 0 {
+		stack := make([]map[string]any, 0, len(je.Stack))
+		for _, fr := range je.Stack {
+			stack = append(stack, map[string]any{
+				"function": fr.Function,
+				"file":     fr.File,
+				"line":     fr.Line,
+			})
+		}
+		result["stack"] = stack
+	}
+	return result
+}
+
+// mapCause converts a cause of the JSON representation (see jsonCause) for ToMap
+func mapCause(cause any) any {
+	switch ct := cause.(type) {
+	case jsonError:
+		return ct.toMap()
+	case interface{ ToMap() map[string]any }:
+		return ct.ToMap()
+	}
+	return cause
+}
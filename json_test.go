@@ -0,0 +1,112 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestError_MarshalJSON(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := Wrap(New("inner").WithCause(errors.New("cause")), "outer")
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	obj := map[string]any{}
+	require.NoError(t, json.Unmarshal(data, &obj))
+	require.Equal(t, "outer", obj["message"])
+	require.Contains(t, obj, "stack")
+	stack := obj["stack"].([]any)
+	require.Len(t, stack, 1)
+	frame := stack[0].(map[string]any)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestError_MarshalJSON", frame["function"])
+	require.Contains(t, frame["file"], "json_test.go")
+	line, ok := frame["line"].(float64)
+	require.True(t, ok)
+	require.Equal(t, float64(16), line)
+	require.Equal(t, line, float64(int(line)))
+
+	cause := obj["cause"].(map[string]any)
+	require.Equal(t, "inner", cause["message"])
+	require.Equal(t, "cause", cause["cause"])
+	require.Contains(t, cause, "stack")
+	require.NotContains(t, obj, "causes")
+}
+
+func TestError_MarshalJSON_NoFrameFormatter(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := New("fooey")
+	require.NotEmpty(t, e.StackInfo())
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey"}`, string(data))
+}
+
+func TestError_MarshalJSON_Joined(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := Join(errors.New("first"), New("second"))
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"first\nsecond","causes":["first",{"message":"second"}]}`, string(data))
+}
+
+func TestError_MarshalJSON_Goroutine(t *testing.T) {
+	DefaultFrameFormatter = nil
+	CaptureGoroutineID = true
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+		CaptureGoroutineID = false
+	}()
+	e := New("fooey")
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf(`{"message":"fooey","goroutine":%d}`, e.Goroutine()), string(data))
+}
+
+func TestError_ToMap(t *testing.T) {
+	inner := New("inner").WithCode("inner-code")
+	e := Wrap(inner, "outer").WithCode("code").WithSeverity(SeverityWarn).WithField("foo", "bar")
+	m := e.ToMap()
+	require.Equal(t, "outer", m["message"])
+	require.Equal(t, "code", m["code"])
+	require.Equal(t, SeverityWarn, m["severity"])
+	require.Equal(t, map[string]any{"foo": "bar"}, m["fields"])
+	_, ok := m["category"]
+	require.False(t, ok)
+	_, ok = m["causes"]
+	require.False(t, ok)
+	stack, ok := m["stack"].([]map[string]any)
+	require.True(t, ok)
+	require.Len(t, stack, len(e.StackInfo()))
+	require.Equal(t, e.StackInfo()[0].Function, stack[0]["function"])
+	require.Equal(t, e.StackInfo()[0].File, stack[0]["file"])
+	require.Equal(t, e.StackInfo()[0].Line, stack[0]["line"])
+
+	cause, ok := m["cause"].(map[string]any)
+	require.True(t, ok)
+	require.Equal(t, "inner", cause["message"])
+	require.Equal(t, "inner-code", cause["code"])
+	require.NotEmpty(t, cause["stack"])
+	_, ok = cause["cause"]
+	require.False(t, ok)
+
+	m = New("fooey").WithCauses(errors.New("a"), New("b")).ToMap()
+	causes, ok := m["causes"].([]any)
+	require.True(t, ok)
+	require.Len(t, causes, 2)
+	require.Equal(t, "a", causes[0])
+	require.Equal(t, "b", causes[1].(map[string]any)["message"])
+
+	m = Wrap(errors.New("plain"), "fooey").ToMap()
+	require.Equal(t, "plain", m["cause"])
+}
@@ -0,0 +1,31 @@
+package stackerr
+
+// Localizer, when set, is used to produce the (localized) message of errors created with NewKeyed - from the message key
+// and the fields of the error
+//
+// If the Localizer returns false (or is nil), the fallback message is used
+var Localizer func(key string, fields map[string]any) (string, bool)
+
+// NewKeyed creates a new StackError with stack info, a message key (for localization) and a fallback message
+//
+// When the error is output (e.g. Error, formatting or marshalling), the message is produced by Localizer - falling back
+// to the fallback message if no Localizer is set or the key is not found
+func NewKeyed(key string, fallback string) StackError {
+	e := allocError(fallback, getStackInfo(1, newOptions()), nil)
+	e.key = key
+	return e.created()
+}
+
+func (e *err) Key() string {
+	return e.key
+}
+
+// text returns the message of the error - localized, if the error has a message key and the Localizer provides it
+func (e *err) text() string {
+	if e.key != "" && Localizer != nil {
+		if msg, ok := Localizer(e.key, e.fields); ok {
+			return msg
+		}
+	}
+	return e.message
+}
@@ -0,0 +1,44 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewKeyed(t *testing.T) {
+	e := NewKeyed("user.not_found", "user not found")
+	require.Equal(t, "user.not_found", e.Key())
+	require.Equal(t, "user not found", e.Error())
+	require.Equal(t, "user not found", fmt.Sprintf("%v", e))
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestNewKeyed", e.StackInfo()[0].Function)
+	require.Equal(t, "", New("fooey").Key())
+}
+
+func TestLocalizer(t *testing.T) {
+	Localizer = func(key string, fields map[string]any) (string, bool) {
+		if key == "user.not_found" {
+			return fmt.Sprintf("utilisateur %v introuvable", fields["id"]), true
+		}
+		return "", false
+	}
+	defer func() {
+		Localizer = nil
+	}()
+	e := NewKeyed("user.not_found", "user not found").WithField("id", 42)
+	require.Equal(t, "utilisateur 42 introuvable", e.Error())
+	require.Equal(t, "utilisateur 42 introuvable", fmt.Sprintf("%v", e))
+	require.Equal(t, "utilisateur 42 introuvable", fmt.Sprintf("%s", e))
+	require.Equal(t, "utilisateur 42 introuvable: cause", e.WithCause(errors.New("cause")).FullMessage())
+	require.Equal(t, "outer: utilisateur 42 introuvable", Wrap(e, "outer").FullMessage())
+	data, err := e.(*err).MarshalJSON()
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"message":"utilisateur 42 introuvable"`)
+	require.Equal(t, "context: utilisateur 42 introuvable", e.WithMessage("context").Error())
+
+	// unknown key falls back...
+	require.Equal(t, "something else", NewKeyed("other", "something else").Error())
+	// non keyed errors are unaffected...
+	require.Equal(t, "user.not_found", New("user.not_found").Error())
+}
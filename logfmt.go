@@ -0,0 +1,53 @@
+package stackerr
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Logfmt returns a single line logfmt (key=value) representation of the error
+//
+// e.g. `msg="not found" cause="sql: no rows" code=NOT_FOUND caller=users.go:42 field.id=123`
+//
+// The keys are "msg" and (when present) "cause", "code", "severity", "caller" and "field.<key>" for each field (in key order).
+// Values containing spaces, quotes or equals signs (or empty values) are quoted
+func (e *err) Logfmt() string {
+	return buildString(func(sb stringBuffer) {
+		e.writeLogfmt(sb)
+	})
+}
+
+func (e *err) writeLogfmt(sb stringBuffer) {
+	writeLogfmt(sb, "msg", e.text())
+	if e.cause != nil {
+		writeLogfmt(sb, "cause", fullMessage(e.cause))
+	}
+	if e.code != "" {
+		writeLogfmt(sb, "code", e.code)
+	}
+	if e.severity != "" {
+		writeLogfmt(sb, "severity", string(e.severity))
+	}
+	if loc := e.Location(); loc != "" {
+		writeLogfmt(sb, "caller", loc)
+	}
+	for _, k := range slices.Sorted(maps.Keys(e.fields)) {
+		writeLogfmt(sb, "field."+k, fmt.Sprintf("%v", e.fields[k]))
+	}
+}
+
+func writeLogfmt(sb stringBuffer, key string, value string) {
+	if sb.Len() > 0 {
+		_ = sb.WriteByte(' ')
+	}
+	_, _ = sb.WriteString(key)
+	_ = sb.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+		_, _ = sb.WriteString(strconv.Quote(value))
+	} else {
+		_, _ = sb.WriteString(value)
+	}
+}
@@ -0,0 +1,28 @@
+package stackerr
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestError_Logfmt(t *testing.T) {
+	e := New("not found")
+	loc := e.Location()
+	require.Equal(t, `msg="not found" caller=`+loc, e.Logfmt())
+
+	e = Wrap(errors.New("sql: no rows"), "lookup").WithCode("NOT_FOUND").WithSeverity(SeverityWarn).
+		WithField("id", 123).WithField("name", "some one")
+	loc = e.Location()
+	require.Equal(t, `msg=lookup cause="sql: no rows" code=NOT_FOUND severity=warn caller=`+loc+` field.id=123 field.name="some one"`, e.Logfmt())
+
+	require.Equal(t, `msg="" caller=`+New("").Location(), New("").Logfmt())
+	require.Contains(t, New(`say "hi"`).Logfmt(), `msg="say \"hi\""`)
+	require.Contains(t, New("a=b").Logfmt(), `msg="a=b"`)
+
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	require.Equal(t, `msg=fooey`, New("fooey").Logfmt())
+}
@@ -0,0 +1,279 @@
+package stackerr
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+func (e *err) WithCode(code string) StackError {
+	r := e.clone()
+	r.code = code
+	return r
+}
+
+func (e *err) Code() string {
+	return e.code
+}
+
+// CodeOf walks the Unwrap chain of the error and returns the first non-empty code found (see StackError.WithCode)
+func CodeOf(err error) (string, bool) {
+	return firstInChain(err, func(err error) (string, bool) {
+		if c, ok := err.(interface{ Code() string }); ok && c.Code() != "" {
+			return c.Code(), true
+		}
+		return "", false
+	})
+}
+
+func (e *err) WithTraceID(id string) StackError {
+	r := e.clone()
+	r.traceID = id
+	return r
+}
+
+func (e *err) TraceID() string {
+	return e.traceID
+}
+
+// TraceIDOf walks the Unwrap chain of the error and returns the first non-empty trace ID found (see StackError.WithTraceID)
+//
+// This allows the trace ID set where an error originated to be correlated where the (wrapped) error is eventually logged
+func TraceIDOf(err error) string {
+	result, _ := firstInChain(err, func(err error) (string, bool) {
+		if t, ok := err.(interface{ TraceID() string }); ok && t.TraceID() != "" {
+			return t.TraceID(), true
+		}
+		return "", false
+	})
+	return result
+}
+
+func (e *err) WithHTTPStatus(status int) StackError {
+	r := e.clone()
+	r.httpStatus = status
+	return r
+}
+
+func (e *err) HTTPStatus() int {
+	return e.httpStatus
+}
+
+// HTTPStatusOf walks the Unwrap chain of the error and returns the first explicit HTTP status found (see StackError.WithHTTPStatus)
+//
+// If no explicit HTTP status is found, the default HTTP status of the first category found is used (see CategoryOf and
+// CategoryHTTPStatus) - and if there is no category, http.StatusInternalServerError is returned.
+// Only error statuses (400 and above) are considered explicit - so a non-nil error never yields a success status
+// (if err is nil, http.StatusOK is returned)
+func HTTPStatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	status, ok := firstInChain(err, func(err error) (int, bool) {
+		if s, ok := err.(interface{ HTTPStatus() int }); ok && s.HTTPStatus() >= http.StatusBadRequest {
+			return s.HTTPStatus(), true
+		}
+		return 0, false
+	})
+	if !ok {
+		return CategoryHTTPStatus(CategoryOf(err))
+	}
+	return status
+}
+
+func (e *err) WithExitCode(code int) StackError {
+	r := e.clone()
+	r.exitCode = code
+	return r
+}
+
+func (e *err) ExitCode() int {
+	return e.exitCode
+}
+
+// ExitCodeOf walks the Unwrap chain of the error and returns the first explicit (non-zero) process exit code found
+// (see StackError.WithExitCode)
+//
+// If no explicit exit code is found, 1 is returned - so a non-nil error never yields a zero (success) exit code
+// (if err is nil, 0 is returned). This allows, for example, a main function to use os.Exit(stackerr.ExitCodeOf(err))
+func ExitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	code, ok := firstInChain(err, func(err error) (int, bool) {
+		if c, ok := err.(interface{ ExitCode() int }); ok && c.ExitCode() != 0 {
+			return c.ExitCode(), true
+		}
+		return 0, false
+	})
+	if !ok {
+		return 1
+	}
+	return code
+}
+
+// Severity is the severity level of an error (see StackError.WithSeverity)
+type Severity string
+
+const (
+	SeverityWarn  Severity = "warn"
+	SeverityError Severity = "error"
+	SeverityFatal Severity = "fatal"
+)
+
+func (e *err) WithSeverity(severity Severity) StackError {
+	r := e.clone()
+	r.severity = severity
+	return r
+}
+
+func (e *err) Severity() Severity {
+	if e.severity == "" {
+		return SeverityError
+	}
+	return e.severity
+}
+
+// SeverityOf walks the Unwrap chain of the error and returns the first explicit severity found (see StackError.WithSeverity)
+//
+// If no explicit severity is found, SeverityError is returned
+func SeverityOf(err error) Severity {
+	if s, ok := firstInChain(err, explicitSeverity); ok {
+		return s
+	}
+	return SeverityError
+}
+
+func explicitSeverity(e error) (Severity, bool) {
+	if se, ok := e.(*err); ok && se.severity != "" {
+		return se.severity, true
+	}
+	return "", false
+}
+
+// flag is an optional bool (distinguishing explicitly false from not set)
+type flag uint8
+
+const (
+	flagUnset flag = iota
+	flagFalse
+	flagTrue
+)
+
+func flagOf(b bool) flag {
+	if b {
+		return flagTrue
+	}
+	return flagFalse
+}
+
+func (e *err) WithTemporary(temporary bool) StackError {
+	r := e.clone()
+	r.temporary = flagOf(temporary)
+	return r
+}
+
+func (e *err) Temporary() bool {
+	return e.temporary == flagTrue
+}
+
+func (e *err) WithTimeout(timeout bool) StackError {
+	r := e.clone()
+	r.timeout = flagOf(timeout)
+	return r
+}
+
+func (e *err) Timeout() bool {
+	return e.timeout == flagTrue
+}
+
+// IsTemporary walks the Unwrap chain of the error and returns the first explicit temporary flag found
+// (see StackError.WithTemporary) or the Temporary() of the first error that implements it (e.g. net.Error)
+func IsTemporary(err error) bool {
+	result, _ := firstInChain(err, explicitTemporary)
+	return result
+}
+
+func explicitTemporary(e error) (bool, bool) {
+	if se, ok := e.(*err); ok {
+		return se.Temporary(), se.temporary != flagUnset
+	} else if t, ok := e.(interface{ Temporary() bool }); ok {
+		return t.Temporary(), true
+	}
+	return false, false
+}
+
+// IsTimeout walks the Unwrap chain of the error and returns the first explicit timeout flag found
+// (see StackError.WithTimeout) or the Timeout() of the first error that implements it (e.g. net.Error)
+func IsTimeout(err error) bool {
+	result, _ := firstInChain(err, explicitTimeout)
+	return result
+}
+
+func explicitTimeout(e error) (bool, bool) {
+	if se, ok := e.(*err); ok {
+		return se.Timeout(), se.timeout != flagUnset
+	} else if t, ok := e.(interface{ Timeout() bool }); ok {
+		return t.Timeout(), true
+	}
+	return false, false
+}
+
+func (e *err) WithRetryable(retryable bool) StackError {
+	r := e.clone()
+	r.retryable = flagOf(retryable)
+	return r
+}
+
+func (e *err) Retryable() bool {
+	return e.retryable == flagTrue
+}
+
+func (e *err) WithRetryAfter(d time.Duration) StackError {
+	r := e.clone()
+	r.retryAfter = d
+	return r
+}
+
+func (e *err) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// IsRetryable walks the Unwrap chain of the error and returns the first explicit retryable flag found
+// (see StackError.WithRetryable) - or false if no retryable flag has been set
+func IsRetryable(err error) bool {
+	result, _ := firstInChain(err, explicitRetryable)
+	return result
+}
+
+func explicitRetryable(e error) (bool, bool) {
+	if se, ok := e.(*err); ok {
+		return se.Retryable(), se.retryable != flagUnset
+	}
+	return false, false
+}
+
+func (e *err) writeMetadata(w io.Writer) {
+	if e.traceID != "" {
+		_, _ = fmt.Fprintf(w, "\nTrace ID: %s", e.traceID)
+	}
+	if e.severity != "" {
+		_, _ = fmt.Fprintf(w, "\nSeverity: %s", e.severity)
+	}
+	if !e.timestamp.IsZero() {
+		_, _ = fmt.Fprintf(w, "\nTime: %s", e.timestamp.Format(time.RFC3339))
+	}
+	if e.goroutine != 0 {
+		_, _ = fmt.Fprintf(w, "\nGoroutine: %d", e.goroutine)
+	}
+	if e.httpStatus != 0 {
+		_, _ = fmt.Fprintf(w, "\nHTTP Status: %d", e.httpStatus)
+	}
+	if e.exitCode != 0 {
+		_, _ = fmt.Fprintf(w, "\nExit Code: %d", e.exitCode)
+	}
+	if e.category != "" {
+		_, _ = fmt.Fprintf(w, "\nCategory: %s", e.category)
+	}
+}
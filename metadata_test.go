@@ -0,0 +1,287 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestError_WithCode(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, "", e.Code())
+	e2 := e.WithCode("NOT_FOUND")
+	require.Equal(t, "", e.Code())
+	require.Equal(t, "NOT_FOUND", e2.Code())
+}
+
+func TestCodeOf(t *testing.T) {
+	e := Wrap(Wrap(New("inner").WithCode("NOT_FOUND"), "middle"), "outer")
+	code, ok := CodeOf(e)
+	require.True(t, ok)
+	require.Equal(t, "NOT_FOUND", code)
+
+	code, ok = CodeOf(fmt.Errorf("plain: %w", e))
+	require.True(t, ok)
+	require.Equal(t, "NOT_FOUND", code)
+
+	code, ok = CodeOf(Wrap(e, "outermost").WithCode("OTHER"))
+	require.True(t, ok)
+	require.Equal(t, "OTHER", code)
+
+	_, ok = CodeOf(Wrap(errors.New("cause"), "fooey"))
+	require.False(t, ok)
+	_, ok = CodeOf(nil)
+	require.False(t, ok)
+}
+
+func TestError_MarshalJSON_Code(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	data, err := json.Marshal(New("fooey").WithCode("NOT_FOUND"))
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey","code":"NOT_FOUND"}`, string(data))
+}
+
+func TestError_WithTraceID(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, "", e.TraceID())
+	e2 := e.WithTraceID("abc123")
+	require.Equal(t, "", e.TraceID())
+	require.Equal(t, "abc123", e2.TraceID())
+}
+
+func TestTraceIDOf(t *testing.T) {
+	e := Wrap(Wrap(New("inner").WithTraceID("abc123"), "middle"), "outer")
+	require.Equal(t, "abc123", TraceIDOf(e))
+	require.Equal(t, "abc123", TraceIDOf(fmt.Errorf("plain: %w", e)))
+	require.Equal(t, "def456", TraceIDOf(Wrap(e, "outermost").WithTraceID("def456")))
+	require.Equal(t, "", TraceIDOf(Wrap(errors.New("cause"), "fooey")))
+	require.Equal(t, "", TraceIDOf(errors.New("plain")))
+	require.Equal(t, "", TraceIDOf(nil))
+}
+
+func TestError_FormatTraceID(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := New("fooey").WithTraceID("abc123")
+	require.Equal(t, "fooey\nTrace ID: abc123", fmt.Sprintf("%+v", e))
+	require.Equal(t, "fooey", fmt.Sprintf("%v", e))
+	require.Equal(t, "fooey", fmt.Sprintf("%+v", New("fooey")))
+}
+
+func TestError_MarshalJSON_TraceID(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	data, err := json.Marshal(New("fooey").WithTraceID("abc123"))
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey","trace_id":"abc123"}`, string(data))
+	data, err = json.Marshal(Wrap(New("inner").WithTraceID("abc123"), "outer"))
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"outer","cause":{"message":"inner","trace_id":"abc123"}}`, string(data))
+	require.Equal(t, "abc123", New("fooey").WithTraceID("abc123").ToMap()["trace_id"])
+}
+
+func TestError_WithExitCode(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, 0, e.ExitCode())
+	e2 := e.WithExitCode(3)
+	require.Equal(t, 0, e.ExitCode())
+	require.Equal(t, 3, e2.ExitCode())
+}
+
+func TestExitCodeOf(t *testing.T) {
+	e := Wrap(Wrap(New("inner").WithExitCode(3), "middle"), "outer")
+	require.Equal(t, 3, ExitCodeOf(e))
+	require.Equal(t, 3, ExitCodeOf(fmt.Errorf("plain: %w", e)))
+	require.Equal(t, 4, ExitCodeOf(Wrap(e, "outermost").WithExitCode(4)))
+	require.Equal(t, 3, ExitCodeOf(Wrap(e, "outermost").WithExitCode(0)))
+	// defaults...
+	require.Equal(t, 1, ExitCodeOf(New("fooey")))
+	require.Equal(t, 1, ExitCodeOf(New("fooey").WithExitCode(0)))
+	require.Equal(t, 1, ExitCodeOf(errors.New("plain")))
+	require.Equal(t, 0, ExitCodeOf(nil))
+}
+
+func TestError_FormatExitCode(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.Equal(t, "fooey\nExit Code: 3", fmt.Sprintf("%+v", New("fooey").WithExitCode(3)))
+	require.Equal(t, "fooey", fmt.Sprintf("%v", New("fooey").WithExitCode(3)))
+	require.Equal(t, "fooey", fmt.Sprintf("%+v", New("fooey").WithExitCode(0)))
+}
+
+func TestError_WithHTTPStatus(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, 0, e.HTTPStatus())
+	e2 := e.WithHTTPStatus(http.StatusNotFound)
+	require.Equal(t, 0, e.HTTPStatus())
+	require.Equal(t, http.StatusNotFound, e2.HTTPStatus())
+}
+
+func TestHTTPStatusOf(t *testing.T) {
+	e := Wrap(Wrap(New("inner").WithHTTPStatus(http.StatusNotFound), "middle"), "outer")
+	require.Equal(t, http.StatusNotFound, HTTPStatusOf(e))
+	require.Equal(t, http.StatusNotFound, HTTPStatusOf(fmt.Errorf("plain: %w", e)))
+	require.Equal(t, http.StatusConflict, HTTPStatusOf(Wrap(e, "outermost").WithHTTPStatus(http.StatusConflict)))
+	require.Equal(t, http.StatusNotFound, HTTPStatusOf(Wrap(e, "outermost").WithHTTPStatus(http.StatusOK)))
+	require.Equal(t, http.StatusInternalServerError, HTTPStatusOf(New("fooey")))
+	require.Equal(t, http.StatusInternalServerError, HTTPStatusOf(errors.New("fooey")))
+	require.Equal(t, http.StatusInternalServerError, HTTPStatusOf(New("fooey").WithHTTPStatus(http.StatusOK)))
+	require.Equal(t, http.StatusOK, HTTPStatusOf(nil))
+
+	// falls back to category...
+	require.Equal(t, http.StatusNotFound, HTTPStatusOf(NotFound("fooey")))
+	require.Equal(t, http.StatusNotFound, HTTPStatusOf(Wrap(NotFound("fooey"), "outer")))
+	require.Equal(t, http.StatusConflict, HTTPStatusOf(NotFound("fooey").WithHTTPStatus(http.StatusConflict)))
+	require.Equal(t, http.StatusConflict, HTTPStatusOf(Wrap(NotFound("fooey"), "outer").WithHTTPStatus(http.StatusConflict)))
+	require.Equal(t, http.StatusBadRequest, HTTPStatusOf(New("fooey").WithCategory(CategoryInvalid)))
+}
+
+func TestError_FormatHTTPStatus(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := New("fooey").WithHTTPStatus(http.StatusNotFound)
+	require.Equal(t, "fooey\nHTTP Status: 404", fmt.Sprintf("%+v", e))
+	require.Equal(t, "fooey", fmt.Sprintf("%v", e))
+}
+
+func TestCaptureTimestamp(t *testing.T) {
+	require.True(t, New("fooey").Timestamp().IsZero())
+
+	CaptureTimestamp = true
+	DefaultFrameFormatter = nil
+	defer func() {
+		CaptureTimestamp = false
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := New("fooey")
+	require.WithinDuration(t, time.Now(), e.Timestamp(), time.Second)
+	require.Equal(t, e.Timestamp(), e.WithCause(nil).Timestamp())
+	require.Equal(t, e.Timestamp(), e.WithField("foo", "bar").Timestamp())
+	require.Equal(t, "fooey\nTime: "+e.Timestamp().Format(time.RFC3339), fmt.Sprintf("%+v", e))
+
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	obj := map[string]any{}
+	require.NoError(t, json.Unmarshal(data, &obj))
+	ts, err := time.Parse(time.RFC3339Nano, obj["time"].(string))
+	require.NoError(t, err)
+	require.True(t, ts.Equal(e.Timestamp()))
+
+	CaptureTimestamp = false
+	data, err = json.Marshal(New("fooey"))
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey"}`, string(data))
+}
+
+func TestError_WithSeverity(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, SeverityError, e.Severity())
+	e2 := e.WithSeverity(SeverityWarn)
+	require.Equal(t, SeverityError, e.Severity())
+	require.Equal(t, SeverityWarn, e2.Severity())
+}
+
+func TestSeverityOf(t *testing.T) {
+	require.Equal(t, SeverityError, SeverityOf(New("fooey")))
+	require.Equal(t, SeverityError, SeverityOf(errors.New("fooey")))
+	e := Wrap(Wrap(New("inner").WithSeverity(SeverityFatal), "middle"), "outer")
+	require.Equal(t, SeverityError, e.Severity())
+	require.Equal(t, SeverityFatal, SeverityOf(e))
+	require.Equal(t, SeverityFatal, SeverityOf(fmt.Errorf("plain: %w", e)))
+	require.Equal(t, SeverityWarn, SeverityOf(Wrap(e, "outermost").WithSeverity(SeverityWarn)))
+}
+
+func TestError_FormatSeverity(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.Equal(t, "fooey", fmt.Sprintf("%+v", New("fooey")))
+	require.Equal(t, "fooey\nSeverity: warn", fmt.Sprintf("%+v", New("fooey").WithSeverity(SeverityWarn)))
+
+	data, err := json.Marshal(New("fooey").WithSeverity(SeverityFatal))
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey","severity":"fatal"}`, string(data))
+}
+
+func TestError_WithTemporary(t *testing.T) {
+	e := New("fooey")
+	require.False(t, e.Temporary())
+	require.True(t, e.WithTemporary(true).Temporary())
+	require.False(t, e.WithTemporary(true).WithTemporary(false).Temporary())
+	require.False(t, e.Temporary())
+	var ne net.Error = e.WithTimeout(true)
+	require.True(t, ne.Timeout())
+}
+
+func TestIsTemporary(t *testing.T) {
+	require.False(t, IsTemporary(nil))
+	require.False(t, IsTemporary(errors.New("fooey")))
+	require.False(t, IsTemporary(New("fooey")))
+	e := Wrap(Wrap(New("inner").WithTemporary(true), "middle"), "outer")
+	require.True(t, IsTemporary(e))
+	require.True(t, IsTemporary(fmt.Errorf("plain: %w", e)))
+	require.False(t, IsTemporary(Wrap(e, "outermost").WithTemporary(false)))
+
+	dnsErr := &net.DNSError{Err: "lookup failed", IsTemporary: true}
+	require.True(t, IsTemporary(Wrap(Wrap(dnsErr, "inner"), "outer")))
+	require.False(t, IsTemporary(Wrap(dnsErr, "inner").WithTemporary(false)))
+	require.False(t, IsTemporary(Wrap(&net.DNSError{Err: "lookup failed"}, "inner")))
+}
+
+func TestIsTimeout(t *testing.T) {
+	require.False(t, IsTimeout(nil))
+	require.False(t, IsTimeout(errors.New("fooey")))
+	require.False(t, IsTimeout(New("fooey")))
+	e := Wrap(Wrap(New("inner").WithTimeout(true), "middle"), "outer")
+	require.True(t, IsTimeout(e))
+	require.True(t, IsTimeout(fmt.Errorf("plain: %w", e)))
+	require.False(t, IsTimeout(Wrap(e, "outermost").WithTimeout(false)))
+
+	dnsErr := &net.DNSError{Err: "lookup failed", IsTimeout: true}
+	require.True(t, IsTimeout(Wrap(Wrap(dnsErr, "inner"), "outer")))
+	require.False(t, IsTimeout(Wrap(dnsErr, "inner").WithTimeout(false)))
+}
+
+func TestError_WithRetryable(t *testing.T) {
+	e := New("fooey")
+	require.False(t, e.Retryable())
+	require.True(t, e.WithRetryable(true).Retryable())
+	require.False(t, e.WithRetryable(true).WithRetryable(false).Retryable())
+	require.False(t, e.Retryable())
+}
+
+func TestIsRetryable(t *testing.T) {
+	require.False(t, IsRetryable(nil))
+	require.False(t, IsRetryable(errors.New("fooey")))
+	require.False(t, IsRetryable(New("fooey")))
+	e := Wrap(Wrap(New("inner").WithRetryable(true), "middle"), "outer")
+	require.True(t, IsRetryable(e))
+	require.True(t, IsRetryable(fmt.Errorf("plain: %w", e)))
+	require.False(t, IsRetryable(Wrap(e, "outermost").WithRetryable(false)))
+}
+
+func TestError_WithRetryAfter(t *testing.T) {
+	e := New("fooey")
+	require.Equal(t, time.Duration(0), e.RetryAfter())
+	e2 := e.WithRetryAfter(5 * time.Second)
+	require.Equal(t, 5*time.Second, e2.RetryAfter())
+	require.Equal(t, time.Duration(0), e.RetryAfter())
+	require.Equal(t, 5*time.Second, e2.WithRetryable(true).RetryAfter())
+}
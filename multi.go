@@ -0,0 +1,71 @@
+package stackerr
+
+import (
+	"fmt"
+	"slices"
+)
+
+// MultiError accumulates errors (e.g. for validation) - see ErrorOrNil
+//
+// The zero value is ready to use. The stack info of the MultiError is captured at the first Add (or Addf) and the
+// stack info of each added StackError is preserved.
+// Note: MultiError is not safe for concurrent use
+type MultiError struct {
+	errs  []error
+	stack callStack
+}
+
+var _ error = (*MultiError)(nil)
+
+// NewMultiError creates a new, empty, MultiError
+func NewMultiError() *MultiError {
+	return &MultiError{}
+}
+
+// Add adds the error (nil errors are ignored)
+func (m *MultiError) Add(err error) {
+	if err != nil {
+		if len(m.errs) == 0 {
+			m.stack = getStackInfo(1, newOptions())
+		}
+		m.errs = append(m.errs, err)
+	}
+}
+
+// Addf adds a new StackError (with stack info) with the formatted message
+func (m *MultiError) Addf(format string, args ...any) {
+	si := getStackInfo(1, newOptions())
+	if len(m.errs) == 0 {
+		m.stack = si
+	}
+	m.errs = append(m.errs, newError(fmt.Sprintf(format, args...), si, nil))
+}
+
+// Len returns the number of errors added
+func (m *MultiError) Len() int {
+	return len(m.errs)
+}
+
+// Error returns the messages of all the errors added, separated by newlines (as with Join)
+func (m *MultiError) Error() string {
+	return joinMessages(m.errs)
+}
+
+// Unwrap returns the errors added (so that errors.Is and errors.As match against any of them)
+func (m *MultiError) Unwrap() []error {
+	return m.errs
+}
+
+// ErrorOrNil returns nil if no errors have been added - otherwise a StackError combining the errors added (as with Join)
+//
+// The stack info of the returned StackError is that captured at the first Add (or Addf)
+func (m *MultiError) ErrorOrNil() error {
+	if len(m.errs) == 0 {
+		return nil
+	}
+	errs := slices.Clone(m.errs)
+	e := allocError(joinMessages(errs), m.stack, nil)
+	e.causes = errs
+	e.joined = true
+	return e.created()
+}
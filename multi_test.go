@@ -0,0 +1,95 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestMultiError_Empty(t *testing.T) {
+	m := NewMultiError()
+	require.Equal(t, 0, m.Len())
+	require.NoError(t, m.ErrorOrNil())
+	require.Equal(t, "", m.Error())
+	m.Add(nil)
+	require.Equal(t, 0, m.Len())
+	require.NoError(t, m.ErrorOrNil())
+
+	var zero MultiError
+	require.NoError(t, zero.ErrorOrNil())
+}
+
+func TestMultiError_Single(t *testing.T) {
+	sentinel := errors.New("name is required")
+	var m MultiError
+	var line int
+	m.Add(causeAt(sentinel, &line))
+	require.Equal(t, 1, m.Len())
+	require.Equal(t, "name is required", m.Error())
+	err := m.ErrorOrNil()
+	require.Error(t, err)
+	require.Equal(t, "name is required", err.Error())
+	require.True(t, errors.Is(err, sentinel))
+	require.True(t, errors.Is(&m, sentinel))
+
+	se, ok := err.(StackError)
+	require.True(t, ok)
+	fr, ok := se.CallerFrame()
+	require.True(t, ok)
+	require.True(t, strings.HasSuffix(fr.Function, ".TestMultiError_Single"))
+	require.Equal(t, line, fr.Line)
+}
+
+func TestMultiError_Multiple(t *testing.T) {
+	sentinel1 := errors.New("name is required")
+	sentinel2 := New("age must be positive")
+	m := NewMultiError()
+	var line1, line2 int
+	m.Add(causeAt(sentinel1, &line1))
+	m.Add(nil)
+	m.Addf("email %q is invalid", "foo@")
+	lineNo(&line2)
+	m.Add(Wrap(sentinel2, "wrapped"))
+	require.Equal(t, 3, m.Len())
+	const expect = "name is required\nemail \"foo@\" is invalid\nwrapped"
+	require.Equal(t, expect, m.Error())
+
+	err := m.ErrorOrNil()
+	require.Equal(t, expect, err.Error())
+	require.True(t, errors.Is(err, sentinel1))
+	require.True(t, errors.Is(err, sentinel2))
+	require.False(t, errors.Is(err, errors.New("name is required")))
+	require.True(t, errors.Is(fmt.Errorf("validation: %w", err), sentinel2))
+
+	// own stack captured at first Add...
+	se := err.(StackError)
+	fr, _ := se.CallerFrame()
+	require.Equal(t, line1, fr.Line)
+	// individual stacks preserved...
+	causes := se.Unwraps()
+	require.Len(t, causes, 3)
+	fr, _ = causes[1].(StackError).CallerFrame()
+	require.Equal(t, line2-1, fr.Line)
+	require.True(t, strings.HasSuffix(fr.Function, ".TestMultiError_Multiple"))
+	fr, _ = causes[2].(StackError).CallerFrame()
+	require.Equal(t, line2+1, fr.Line)
+
+	// adding after ErrorOrNil does not affect the returned error...
+	m.Add(errors.New("another"))
+	require.Equal(t, 4, m.Len())
+	require.Len(t, se.Unwraps(), 3)
+	require.Equal(t, expect, err.Error())
+}
+
+func TestMultiError_Addf_First(t *testing.T) {
+	var m MultiError
+	var line int
+	m.Addf("fooey %d", 1)
+	lineNo(&line)
+	err := m.ErrorOrNil().(StackError)
+	fr, _ := err.CallerFrame()
+	require.Equal(t, line-1, fr.Line)
+	require.Equal(t, "fooey 1", err.Error())
+}
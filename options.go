@@ -0,0 +1,44 @@
+package stackerr
+
+// Option is an option that can be passed to NewWithOptions or WrapWithOptions
+type Option func(o *options)
+
+// WithMaxDepth is an Option that overrides MaxStackDepth for a single call
+//
+// As with MaxStackDepth, a value of zero means unlimited
+func WithMaxDepth(n uint) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
+
+// WithSkip is an Option that skips the specified number of additional stack frames when capturing stack info
+//
+// This is useful for helper functions that create errors - e.g. a helper can pass WithSkip(1) to attribute
+// the error to its own caller
+//
+// Negative values are treated as zero
+func WithSkip(n int) Option {
+	return func(o *options) {
+		o.skip = max(n, 0)
+	}
+}
+
+type options struct {
+	maxDepth uint
+	skip     int
+	// trimRuntime indicates that leading runtime frames are trimmed (see Recover)
+	trimRuntime bool
+}
+
+func newOptions(opts ...Option) options {
+	result := options{
+		maxDepth: MaxStackDepthValue(),
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&result)
+		}
+	}
+	return result
+}
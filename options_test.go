@@ -0,0 +1,105 @@
+package stackerr
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	e := NewWithOptions("fooey")
+	require.Error(t, e)
+	require.Equal(t, "fooey", e.Error())
+
+	e = recurse(64, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(40))
+	})
+	require.Len(t, e.StackInfo(), 40)
+	e = recurse(64, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(2))
+	})
+	require.Len(t, e.StackInfo(), 2)
+	e = recurse(64, func() StackError {
+		return New("fooey")
+	})
+	require.Len(t, e.StackInfo(), int(MaxStackDepth))
+}
+
+func TestWrapWithOptions(t *testing.T) {
+	e := recurse(64, func() StackError {
+		return WrapWithOptions(errors.New("cause"), "fooey", WithMaxDepth(40), nil)
+	})
+	require.Error(t, e)
+	require.Equal(t, "fooey", e.Error())
+	require.Equal(t, "cause", e.Cause().Error())
+	require.Len(t, e.StackInfo(), 40)
+
+	require.NoError(t, WrapWithOptions(nil, "fooey", WithMaxDepth(40)))
+}
+
+func recurse(depth int, fn func() StackError) StackError {
+	if depth <= 0 {
+		return fn()
+	}
+	return recurse(depth-1, fn)
+}
+
+func TestWithSkip(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	helper := func(err error) StackError {
+		return WrapWithOptions(err, "fooey", WithSkip(1))
+	}
+	e := helper(errors.New("cause"))
+	si := e.StackInfo()
+	require.NotEmpty(t, si)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestWithSkip", si[0].Function)
+	require.Equal(t, 55, si[0].Line)
+
+	t.Run("negative", func(t *testing.T) {
+		e := NewWithOptions("fooey", WithSkip(-1))
+		si := e.StackInfo()
+		require.NotEmpty(t, si)
+		require.Equal(t, 62, si[0].Line)
+	})
+	t.Run("past top of stack", func(t *testing.T) {
+		e := NewWithOptions("fooey", WithSkip(1000))
+		require.NotNil(t, e.StackInfo())
+		require.Empty(t, e.StackInfo())
+	})
+}
+
+func TestMaxStackDepth_Unlimited(t *testing.T) {
+	MaxStackDepth = 0
+	defer func() {
+		MaxStackDepth = 16
+	}()
+	// deeper than the initial buffer size, to ensure the buffer grows...
+	const depth = unlimitedInitialDepth * 3
+	e := recurse(depth, func() StackError {
+		return New("fooey")
+	})
+	si := e.StackInfo()
+	require.Greater(t, len(si), depth)
+	require.Equal(t, "runtime.goexit", si[len(si)-1].Function)
+	count := 0
+	for _, fr := range si {
+		if fr.Function == "github.com/go-andiamo/stackerr.recurse" {
+			count++
+		}
+	}
+	require.Equal(t, depth+1, count)
+
+	e = recurse(depth, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(5))
+	})
+	require.Len(t, e.StackInfo(), 5)
+
+	MaxStackDepth = 16
+	e = recurse(depth, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(0))
+	})
+	require.Greater(t, len(e.StackInfo()), depth)
+}
@@ -0,0 +1,69 @@
+package stackerr
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+var errPool = sync.Pool{
+	New: func() any {
+		return &err{}
+	},
+}
+
+// Release returns the error to the pool (see UsePool) once the caller is done with it
+//
+// WARNING: the error must not be used after it has been released (including any references to it - e.g. as the cause
+// of another error) - as it may be reused by any subsequently created error. Only the error itself is released (its causes are not).
+//
+// Release is a no-op when UsePool is false (or the error is not a StackError created by this package)
+func Release(e StackError) {
+	if !UsePool {
+		return
+	}
+	if pe, ok := e.(*err); ok && pe != nil {
+		*pe = err{}
+		errPool.Put(pe)
+	}
+}
+
+// maxPooledBufferSize is the maximum capacity of buffers returned to the buffer pool (so that the occasional
+// very large output does not pin memory)
+const maxPooledBufferSize = 64 * 1024
+
+var bufPool = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+// stringBuffer is the interface common to strings.Builder and bytes.Buffer used when building strings
+type stringBuffer interface {
+	io.Writer
+	io.StringWriter
+	io.ByteWriter
+	Len() int
+}
+
+// buildString returns the string written by the fn - using a pooled buffer (pre-sized to FormatBufferSize)
+//
+// A bytes.Buffer is pooled (rather than a strings.Builder) as a strings.Builder cannot be reused once its string has been taken.
+// If FormatBufferSize is zero (or less), no pooled buffer is used
+func buildString(fn func(w stringBuffer)) string {
+	if FormatBufferSize <= 0 {
+		var sb strings.Builder
+		fn(&sb)
+		return sb.String()
+	}
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.Grow(FormatBufferSize)
+	fn(buf)
+	result := buf.String()
+	if buf.Cap() <= maxPooledBufferSize {
+		bufPool.Put(buf)
+	}
+	return result
+}
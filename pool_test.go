@@ -0,0 +1,112 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRelease(t *testing.T) {
+	UsePool = true
+	defer func() {
+		UsePool = false
+	}()
+	e := New("fooey").WithCode("code").WithField("foo", "bar").WithSentinel(errors.New("sentinel"))
+	pe := e.(*err)
+	Release(e)
+	require.Equal(t, err{}, *pe)
+
+	for range 10 {
+		e2 := New("fooey2")
+		require.Equal(t, "fooey2", e2.Error())
+		require.Empty(t, e2.Code())
+		require.Empty(t, e2.Fields())
+		require.NoError(t, e2.Cause())
+		require.NotEmpty(t, e2.StackInfo())
+		Release(e2)
+	}
+	Release(nil)
+	Release(Join())
+}
+
+func TestRelease_NoPool(t *testing.T) {
+	e := New("fooey").WithCode("code")
+	Release(e)
+	require.Equal(t, "fooey", e.Error())
+	require.Equal(t, "code", e.Code())
+}
+
+func BenchmarkNew_Pool(b *testing.B) {
+	UsePool = true
+	defer func() {
+		UsePool = false
+	}()
+	b.ReportAllocs()
+	for b.Loop() {
+		Release(New("fooey"))
+	}
+}
+
+func BenchmarkNew_NoPool(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		Release(New("fooey"))
+	}
+}
+
+func TestBuildString(t *testing.T) {
+	defer func() {
+		FormatBufferSize = 512
+	}()
+	e := recurse(20, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(32)).WithCode("code").WithField("foo", "bar baz")
+	})
+	si := e.StackInfo()
+	pooledStack, pooledLogfmt := FormatStack(si, nil), e.Logfmt()
+	// again (with reused buffers)...
+	require.Equal(t, pooledStack, FormatStack(si, nil))
+	require.Equal(t, pooledLogfmt, e.Logfmt())
+
+	FormatBufferSize = 0
+	require.Equal(t, pooledStack, FormatStack(si, nil))
+	require.Equal(t, pooledLogfmt, e.Logfmt())
+	require.True(t, strings.HasSuffix(fmt.Sprintf("%+v", e), pooledStack))
+
+	FormatBufferSize = 8
+	require.Equal(t, pooledStack, FormatStack(si, nil))
+	require.Equal(t, "", FormatStack(nil, nil))
+}
+
+func TestBuildString_LargeNotPooled(t *testing.T) {
+	large := strings.Repeat("x", maxPooledBufferSize+1)
+	require.Equal(t, large, buildString(func(w stringBuffer) {
+		_, _ = w.WriteString(large)
+	}))
+	require.Equal(t, "foo", buildString(func(w stringBuffer) {
+		_, _ = w.WriteString("foo")
+	}))
+}
+
+func benchmarkFormatStack(b *testing.B, bufferSize int) {
+	FormatBufferSize = bufferSize
+	defer func() {
+		FormatBufferSize = 512
+	}()
+	si := recurse(30, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(32))
+	}).StackInfo()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = FormatStack(si, nil)
+	}
+}
+
+func BenchmarkFormatStack_Pooled(b *testing.B) {
+	benchmarkFormatStack(b, 4096)
+}
+
+func BenchmarkFormatStack_Naive(b *testing.B) {
+	benchmarkFormatStack(b, 0)
+}
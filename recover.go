@@ -0,0 +1,95 @@
+package stackerr
+
+import "fmt"
+
+// Recover converts the result of recover() into a StackError
+//
+// It is intended to be called directly from a deferred function, e.g.
+//
+//	defer func() {
+//		if err := stackerr.Recover(recover()); err != nil {
+//			// handle err
+//		}
+//	}()
+//
+// If the recovered value is an error, it is used as the cause of the returned StackError - otherwise,
+// the recovered value is formatted (using %v) into the message
+//
+// The stack info is captured from the caller of the deferred function - skipping the deferred function itself and any
+// runtime (panic handling) frames - so that the first frame is the point at which the panic occurred
+//
+// Returns nil if the recovered value is nil
+func Recover(recovered any) StackError {
+	if recovered == nil {
+		return nil
+	}
+	return fromRecovered(recovered, getStackInfo(1, recoverOptions(1)))
+}
+
+// RecoverTo converts the result of recover() into a StackError and assigns it to the error pointer
+// (typically a named error return), e.g.
+//
+//	func doSomething() (err error) {
+//		defer func() {
+//			stackerr.RecoverTo(&err, recover())
+//		}()
+//		...
+//	}
+//
+// Note: RecoverTo must be called from within a deferred function (i.e. not deferred directly) - as recover() only
+// works when called directly by the deferred function
+//
+// The stack info semantics are the same as Recover - and nothing is assigned if the recovered value is nil
+func RecoverTo(errPtr *error, recovered any) {
+	if recovered == nil || errPtr == nil {
+		return
+	}
+	*errPtr = fromRecovered(recovered, getStackInfo(1, recoverOptions(1)))
+}
+
+// SafeGo runs the func - converting any panic into a StackError (which is returned)
+//
+// This is useful for workers (e.g. in goroutines or worker pools) where a panicking task should become an error
+// rather than crashing the process
+//
+// The stack info of a panic is captured with the same semantics as Recover (so that the first frame is the point at which the panic occurred).
+// Note: if the func returns normally, the error it returned (if any) is returned as-is (i.e. not wrapped with a stack)
+func SafeGo(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fromRecovered(r, getStackInfo(1, recoverOptions(0)))
+		}
+	}()
+	return fn()
+}
+
+func recoverOptions(skip int) options {
+	o := newOptions(WithSkip(skip))
+	o.trimRuntime = true
+	return o
+}
+
+func fromRecovered(recovered any, si callStack) StackError {
+	if err, ok := recovered.(error); ok {
+		return newError("panic", si, err)
+	}
+	return newError(fmt.Sprintf("panic: %v", recovered), si, nil)
+}
+
+// Must returns the value if the error is nil - otherwise, it panics with a StackError wrapping the error
+// (with the same message as the error, as with WithStack)
+//
+// This mirrors the common generic Must helper, e.g.
+//
+//	cfg := stackerr.Must(loadConfig())
+//
+// but ensures that the panic value carries stack info for post-mortem debugging (see Recover).
+// The stack info is captured at the Must call - so the first frame is the caller of Must
+func Must[T any](v T, err error) T {
+	if err != nil {
+		e := allocError(err.Error(), getStackInfo(1, newOptions()), err)
+		e.passthrough = true
+		panic(e.created())
+	}
+	return v
+}
@@ -0,0 +1,180 @@
+package stackerr
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		var e StackError
+		line := 0
+		func() {
+			defer func() {
+				e = Recover(recover())
+			}()
+			panic(fmt.Sprintf("boom %d", lineNo(&line)))
+		}()
+		require.Error(t, e)
+		require.Equal(t, fmt.Sprintf("panic: boom %d", line), e.Error())
+		require.NoError(t, e.Cause())
+		fr, ok := e.CallerFrame()
+		require.True(t, ok)
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestRecover.func1.1", fr.Function)
+		require.Equal(t, line, fr.Line)
+	})
+	t.Run("error", func(t *testing.T) {
+		var e StackError
+		line := 0
+		cause := errors.New("boom")
+		func() {
+			defer func() {
+				e = Recover(recover())
+			}()
+			panic(causeAt(cause, &line))
+		}()
+		require.Error(t, e)
+		require.Equal(t, "panic", e.Error())
+		require.Equal(t, cause, e.Cause())
+		require.True(t, errors.Is(e, cause))
+		require.Equal(t, "panic: boom", fmt.Sprintf("%v", e))
+		fr, ok := e.CallerFrame()
+		require.True(t, ok)
+		require.Equal(t, line, fr.Line)
+	})
+	t.Run("runtime error", func(t *testing.T) {
+		var e StackError
+		line := 0
+		func() {
+			defer func() {
+				e = Recover(recover())
+			}()
+			var m map[string]int
+			m[fmt.Sprint(lineNo(&line))] = 1
+		}()
+		require.Error(t, e)
+		var re runtime.Error
+		require.True(t, errors.As(e, &re))
+		fr, ok := e.CallerFrame()
+		require.True(t, ok)
+		require.Equal(t, line, fr.Line)
+	})
+	t.Run("nil", func(t *testing.T) {
+		var e StackError
+		func() {
+			defer func() {
+				e = Recover(recover())
+			}()
+		}()
+		require.NoError(t, e)
+	})
+}
+
+func TestRecoverTo(t *testing.T) {
+	line := 0
+	fn := func(doPanic bool) (err error) {
+		defer func() {
+			RecoverTo(&err, recover())
+		}()
+		if doPanic {
+			panic(fmt.Sprintf("boom %d", lineNo(&line)))
+		}
+		return nil
+	}
+	err := fn(true)
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("panic: boom %d", line), err.Error())
+	e, ok := err.(StackError)
+	require.True(t, ok)
+	fr, ok := e.CallerFrame()
+	require.True(t, ok)
+	require.Equal(t, line, fr.Line)
+
+	require.NoError(t, fn(false))
+}
+
+// lineNo sets (and returns) the line number of the caller
+func lineNo(line *int) int {
+	_, _, *line, _ = runtime.Caller(1)
+	return *line
+}
+
+func causeAt(cause error, line *int) error {
+	_, _, *line, _ = runtime.Caller(1)
+	return cause
+}
+
+func TestSafeGo(t *testing.T) {
+	t.Run("panic", func(t *testing.T) {
+		line := 0
+		ch := make(chan error, 1)
+		go func() {
+			ch <- SafeGo(func() error {
+				panic(fmt.Sprintf("boom %d", lineNo(&line)))
+			})
+		}()
+		err := <-ch
+		require.Error(t, err)
+		require.Equal(t, fmt.Sprintf("panic: boom %d", line), err.Error())
+		e, ok := err.(StackError)
+		require.True(t, ok)
+		fr, ok := e.CallerFrame()
+		require.True(t, ok)
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestSafeGo.func1.1.1", fr.Function)
+		require.Equal(t, line, fr.Line)
+	})
+	t.Run("panic with error", func(t *testing.T) {
+		cause := errors.New("boom")
+		err := SafeGo(func() error {
+			panic(cause)
+		})
+		require.Error(t, err)
+		require.True(t, errors.Is(err, cause))
+		_, ok := err.(StackError)
+		require.True(t, ok)
+	})
+	t.Run("returned error", func(t *testing.T) {
+		cause := errors.New("fooey")
+		err := SafeGo(func() error {
+			return cause
+		})
+		require.Equal(t, cause, err)
+	})
+	t.Run("no error", func(t *testing.T) {
+		err := SafeGo(func() error {
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestMust(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			require.Equal(t, 42, Must(42, nil))
+		})
+	})
+	t.Run("panics", func(t *testing.T) {
+		cause := errors.New("fooey")
+		line := 0
+		var recovered any
+		func() {
+			defer func() {
+				recovered = recover()
+			}()
+			_ = Must(lineNo(&line), cause)
+		}()
+		e, ok := recovered.(StackError)
+		require.True(t, ok)
+		require.Equal(t, "fooey", e.Error())
+		require.True(t, errors.Is(e, cause))
+		require.Equal(t, cause, e.Unwrap())
+		fr, ok := e.CallerFrame()
+		require.True(t, ok)
+		require.Equal(t, "github.com/go-andiamo/stackerr.TestMust.func2.1", fr.Function)
+		require.Equal(t, line, fr.Line)
+	})
+}
@@ -0,0 +1,25 @@
+package stackerr
+
+import "fmt"
+
+// Redactor, when set, is used by NewfRedacted to produce the message from the format and args
+//
+// e.g. a Redactor might format the message and then mask anything that looks like an email address or token
+var Redactor func(format string, args []any) string
+
+// NewfRedacted creates a new StackError with stack info and a formatted message - where the message is produced by
+// Redactor (e.g. masking sensitive args such as emails or tokens)
+//
+// If Redactor is nil, the message is formatted as with Newf.
+// The format is still retained (see StackError.MessageTemplate and StackError.Fingerprint) - but the args are never retained
+func NewfRedacted(format string, args ...any) StackError {
+	var msg string
+	if Redactor != nil {
+		msg = Redactor(format, args)
+	} else {
+		msg = fmt.Sprintf(format, args...)
+	}
+	e := allocError(msg, getStackInfo(1, newOptions()), nil)
+	e.format = format
+	return e.created()
+}
@@ -0,0 +1,34 @@
+package stackerr
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestNewfRedacted(t *testing.T) {
+	e := NewfRedacted("user %s not found", "someone@example.com")
+	require.Equal(t, "user someone@example.com not found", e.Error())
+
+	rx := regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]+`)
+	Redactor = func(format string, args []any) string {
+		return rx.ReplaceAllString(fmt.Sprintf(format, args...), "****")
+	}
+	CaptureMessageArgs = true
+	defer func() {
+		Redactor = nil
+		CaptureMessageArgs = false
+	}()
+	errs := make([]StackError, 0, 2)
+	for _, email := range []string{"someone@example.com", "other@example.org"} {
+		errs = append(errs, NewfRedacted("user %s not found (%d)", email, 42))
+	}
+	require.Equal(t, "user **** not found (42)", errs[0].Error())
+	require.Equal(t, "user **** not found (42)", fmt.Sprintf("%v", errs[1]))
+	format, args := errs[0].MessageTemplate()
+	require.Equal(t, "user %s not found (%d)", format)
+	require.Nil(t, args)
+	require.Equal(t, errs[0].Fingerprint(), errs[1].Fingerprint())
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestNewfRedacted", errs[0].StackInfo()[0].Function)
+}
@@ -0,0 +1,33 @@
+package stackerr
+
+import (
+	"path"
+	"strings"
+)
+
+// SentryFrame is a stack frame in the shape expected by Sentry (see StackError.SentryFrames)
+type SentryFrame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+}
+
+func (e *err) SentryFrames() []SentryFrame {
+	si := e.StackInfo()
+	result := make([]SentryFrame, len(si))
+	for i, fr := range si {
+		module, _ := packageFromFunction(fr.Function)
+		fn := strings.ReplaceAll(fr.Function, "%2e", ".")
+		// sentry expects frames ordered innermost last...
+		result[len(si)-1-i] = SentryFrame{
+			Function: strings.TrimPrefix(strings.TrimPrefix(fn, module), "."),
+			Module:   module,
+			AbsPath:  fr.File,
+			Filename: path.Base(fr.File),
+			Lineno:   fr.Line,
+		}
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package stackerr
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestError_SentryFrames(t *testing.T) {
+	e := recurse(2, func() StackError {
+		return New("fooey")
+	})
+	si := e.StackInfo()
+	sfs := e.SentryFrames()
+	require.Len(t, sfs, len(si))
+	for i, fr := range si {
+		sf := sfs[len(sfs)-1-i]
+		full, _ := packageFromFunction(fr.Function)
+		require.Equal(t, full, sf.Module)
+		require.Equal(t, fr.File, sf.AbsPath)
+		require.Equal(t, fr.Line, sf.Lineno)
+		require.Equal(t, fr.Function, sf.Module+"."+sf.Function)
+	}
+	innermost := sfs[len(sfs)-1]
+	require.Equal(t, "github.com/go-andiamo/stackerr", innermost.Module)
+	require.Equal(t, "TestError_SentryFrames.func1", innermost.Function)
+	require.Equal(t, "sentry_test.go", innermost.Filename)
+	require.Equal(t, "recurse", sfs[len(sfs)-2].Function)
+	require.Equal(t, "runtime", sfs[0].Module)
+	require.Equal(t, "goexit", sfs[0].Function)
+
+	require.Empty(t, NewWithOptions("fooey", WithSkip(1000)).SentryFrames())
+}
+
+func TestError_SentryFrames_VersionedPackage(t *testing.T) {
+	e := New("fooey").(*err)
+	e.stack = callStack{frames: StackInfo{{Function: "gopkg.in/yaml%2ev3.(*encoder).marshal", File: "/go/yaml/encode.go", Line: 10}}}
+	sfs := e.SentryFrames()
+	require.Len(t, sfs, 1)
+	require.Equal(t, SentryFrame{
+		Function: "(*encoder).marshal",
+		Module:   "gopkg.in/yaml.v3",
+		AbsPath:  "/go/yaml/encode.go",
+		Filename: "encode.go",
+		Lineno:   10,
+	}, sfs[0])
+}
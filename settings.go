@@ -1,8 +1,15 @@
 package stackerr
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
 	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
 )
 
 // PackageFilter is the interface used by DefaultPackageFilter
@@ -12,9 +19,9 @@ type PackageFilter interface {
 
 // SetDefaultPackageFilter sets the DefaultPackageFilter with a filter for the specified package
 func SetDefaultPackageFilter(pkg string) {
-	DefaultPackageFilter = &packageFilter{
+	SetPackageFilter(&packageFilter{
 		packageName: pkg,
-	}
+	})
 }
 
 type packageFilter struct {
@@ -27,14 +34,137 @@ func (pf *packageFilter) Include(packageName string) bool {
 	return pf.packageName == packageName
 }
 
+// SetDefaultPackageFilterPrefix sets the DefaultPackageFilter with a filter for any package whose full path starts with the specified prefix
+//
+// e.g. SetDefaultPackageFilterPrefix("github.com/myorg/myapp") captures frames from the module and all its sub-packages
+func SetDefaultPackageFilterPrefix(prefix string) {
+	SetPackageFilter(PrefixFilter(prefix))
+}
+
+// ErrNoBuildInfo is the error returned by SetDefaultPackageFilterToModule when the build info (or main module path)
+// of the running binary is not available
+var ErrNoBuildInfo = errors.New("build info not available")
+
+// readBuildInfo is debug.ReadBuildInfo (replaceable in tests)
+var readBuildInfo = debug.ReadBuildInfo
+
+// SetDefaultPackageFilterToModule sets the DefaultPackageFilter with a filter that includes only packages in the main module
+// of the running binary (as determined by debug.ReadBuildInfo) - so that only frames from the program's own module are captured
+//
+// returns ErrNoBuildInfo if the build info or main module path is not available (and DefaultPackageFilter is left unchanged)
+func SetDefaultPackageFilterToModule() error {
+	bi, ok := readBuildInfo()
+	if !ok || bi.Main.Path == "" {
+		return ErrNoBuildInfo
+	}
+	SetPackageFilter(AnyFilter(&packageFilter{packageName: bi.Main.Path}, PrefixFilter(bi.Main.Path+"/")))
+	return nil
+}
+
+// SetDefaultPackageFilterRegexp sets the DefaultPackageFilter with a filter that matches the full package path
+// against the specified regular expression pattern
+//
+// returns an error if the pattern cannot be compiled (and DefaultPackageFilter is left unchanged)
+func SetDefaultPackageFilterRegexp(pattern string) error {
+	rx, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	SetPackageFilter(&regexpFilter{
+		rx: rx,
+	})
+	return nil
+}
+
+type regexpFilter struct {
+	rx *regexp.Regexp
+}
+
+var _ PackageFilter = (*regexpFilter)(nil)
+
+func (rf *regexpFilter) Include(packageName string) bool {
+	return rf.rx.MatchString(packageName)
+}
+
 type FrameFormatter interface {
 	StartLine() string
 	FrameLine(frame runtime.Frame) string
 }
 
+// ExtendedFrameFormatter is an optional extension to FrameFormatter
+//
+// When the FrameFormatter used also implements ExtendedFrameFormatter, the FrameSeparator is written
+// between each frame line and the EndLine is written after all frame lines
+type ExtendedFrameFormatter interface {
+	FrameFormatter
+	FrameSeparator() string
+	EndLine() string
+}
+
+// BoundaryFrameFormatter is an optional extension to FrameFormatter
+//
+// When the FrameFormatter used also implements BoundaryFrameFormatter, the BoundaryLine is written before the first
+// frame of each appended stack (see StackError.AppendStack)
+type BoundaryFrameFormatter interface {
+	FrameFormatter
+	BoundaryLine() string
+}
+
+// TruncatedFrameFormatter is an optional extension to FrameFormatter
+//
+// When the FrameFormatter used also implements TruncatedFrameFormatter, the TruncatedLine is written after the last
+// frame line if the stack info was truncated (see StackError.Truncated) - or before the first frame line if StackOrderOuterFirst is set
+type TruncatedFrameFormatter interface {
+	FrameFormatter
+	TruncatedLine() string
+}
+
+// RepeatedFrameFormatter is an optional extension to FrameFormatter
+//
+// When CollapseRecursion is set, consecutive identical frames are only collapsed if the FrameFormatter used also implements
+// RepeatedFrameFormatter - the RepeatedFrameLine is written (instead of the FrameLine) for each frame that was repeated
+// (i.e. repeats is greater than one)
+type RepeatedFrameFormatter interface {
+	FrameFormatter
+	RepeatedFrameLine(frame runtime.Frame, repeats int) string
+}
+
+// CompactFrameFormatter is a FrameFormatter that formats the stack on a single line
+//
+// e.g. `[stack: pkg.Func(file.go:10) <- pkg.Caller(file.go:20)]`
+//
+// to use, set DefaultFrameFormatter = &CompactFrameFormatter{}
+type CompactFrameFormatter struct{}
+
+var _ ExtendedFrameFormatter = (*CompactFrameFormatter)(nil)
+var _ RepeatedFrameFormatter = (*CompactFrameFormatter)(nil)
+
+func (cf *CompactFrameFormatter) StartLine() string {
+	return " [stack: "
+}
+
+func (cf *CompactFrameFormatter) FrameLine(frame runtime.Frame) string {
+	return fmt.Sprintf("%s(%s:%d)", frame.Function, FrameFile(frame), frame.Line)
+}
+
+func (cf *CompactFrameFormatter) RepeatedFrameLine(frame runtime.Frame, repeats int) string {
+	return fmt.Sprintf("%s (x%d)", cf.FrameLine(frame), repeats)
+}
+
+func (cf *CompactFrameFormatter) FrameSeparator() string {
+	return " <- "
+}
+
+func (cf *CompactFrameFormatter) EndLine() string {
+	return "]"
+}
+
 type frameFormatter struct{}
 
-var _ FrameFormatter = (*frameFormatter)(nil)
+var _ SourceFrameFormatter = (*frameFormatter)(nil)
+var _ BoundaryFrameFormatter = (*frameFormatter)(nil)
+var _ TruncatedFrameFormatter = (*frameFormatter)(nil)
+var _ RepeatedFrameFormatter = (*frameFormatter)(nil)
 
 func (ff *frameFormatter) StartLine() string {
 	return "\nStack:"
@@ -44,6 +174,204 @@ func (ff *frameFormatter) FrameLine(frame runtime.Frame) string {
 	return fmt.Sprintf("\n\t%s:%d", frame.Function, frame.Line)
 }
 
+func (ff *frameFormatter) RepeatedFrameLine(frame runtime.Frame, repeats int) string {
+	return fmt.Sprintf("%s (x%d)", ff.FrameLine(frame), repeats)
+}
+
+func (ff *frameFormatter) SourceLine(source string) string {
+	return "\n\t\t" + source
+}
+
+func (ff *frameFormatter) BoundaryLine() string {
+	return "\n\t--- appended stack ---"
+}
+
+func (ff *frameFormatter) TruncatedLine() string {
+	return "\n\t... (truncated)"
+}
+
+// RelativeFrameFormatter is a FrameFormatter that formats each frame as only `package.Function:line` - i.e. with
+// no file path and with the package path trimmed to the package name
+//
+// e.g. `stackerr.TestFoo:10` (rather than `github.com/go-andiamo/stackerr.TestFoo:10`)
+//
+// As the output contains no machine-specific paths, it is stable across machines (and runs) - so is suitable for golden-file tests.
+// To use, set DefaultFrameFormatter = &RelativeFrameFormatter{}
+type RelativeFrameFormatter struct{}
+
+var _ BoundaryFrameFormatter = (*RelativeFrameFormatter)(nil)
+var _ TruncatedFrameFormatter = (*RelativeFrameFormatter)(nil)
+var _ RepeatedFrameFormatter = (*RelativeFrameFormatter)(nil)
+
+func (rf *RelativeFrameFormatter) StartLine() string {
+	return "\nStack:"
+}
+
+func (rf *RelativeFrameFormatter) FrameLine(frame runtime.Frame) string {
+	return fmt.Sprintf("\n\t%s:%d", relativeFunction(frame.Function), frame.Line)
+}
+
+func (rf *RelativeFrameFormatter) RepeatedFrameLine(frame runtime.Frame, repeats int) string {
+	return fmt.Sprintf("%s (x%d)", rf.FrameLine(frame), repeats)
+}
+
+func (rf *RelativeFrameFormatter) BoundaryLine() string {
+	return "\n\t--- appended stack ---"
+}
+
+func (rf *RelativeFrameFormatter) TruncatedLine() string {
+	return "\n\t... (truncated)"
+}
+
+// relativeFunction trims the package path from a function name (e.g. "github.com/me/pkg.(*T).M" becomes "pkg.(*T).M")
+//
+// only slashes before any method receiver or generic type args are considered part of the package path
+func relativeFunction(name string) string {
+	limit := len(name)
+	if i := strings.IndexAny(name, "[("); i >= 0 {
+		limit = i
+	}
+	return name[strings.LastIndexByte(name[:limit], '/')+1:]
+}
+
+// JSONFrameFormatter is a FrameFormatter that formats the stack as a JSON array
+//
+// e.g. `[{"func":"pkg.Func","file":"file.go","line":10},{"func":"pkg.Caller","file":"file.go","line":20}]`
+//
+// (when CollapseRecursion is set, collapsed frames have a "repeats" property - e.g. `{"func":"pkg.Func","file":"file.go","line":10,"repeats":3}`)
+//
+// to use, set DefaultFrameFormatter = &JSONFrameFormatter{}
+type JSONFrameFormatter struct{}
+
+var _ ExtendedFrameFormatter = (*JSONFrameFormatter)(nil)
+var _ RepeatedFrameFormatter = (*JSONFrameFormatter)(nil)
+
+func (jf *JSONFrameFormatter) StartLine() string {
+	return "\n["
+}
+
+func (jf *JSONFrameFormatter) FrameLine(frame runtime.Frame) string {
+	return jf.RepeatedFrameLine(frame, 0)
+}
+
+func (jf *JSONFrameFormatter) RepeatedFrameLine(frame runtime.Frame, repeats int) string {
+	data, _ := json.Marshal(struct {
+		Function string `json:"func"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Repeats  int    `json:"repeats,omitempty"`
+	}{
+		Function: frame.Function,
+		File:     FrameFile(frame),
+		Line:     frame.Line,
+		Repeats:  repeats,
+	})
+	return string(data)
+}
+
+func (jf *JSONFrameFormatter) FrameSeparator() string {
+	return ","
+}
+
+func (jf *JSONFrameFormatter) EndLine() string {
+	return "]"
+}
+
+// TeeFormatter is a FrameFormatter that delegates to the Inner formatter - but also writes the output of the Inner
+// formatter (the start line, each frame line etc.) to the Sink
+//
+// This can be used, for example, to capture stacks to a debug file whilst keeping normal log output lean
+// (e.g. with an Inner formatter that outputs nothing). Errors writing to the Sink are passed to OnError (if set), otherwise they are ignored.
+// The optional extensions (e.g. BoundaryFrameFormatter and TruncatedFrameFormatter) implemented by the Inner formatter are
+// forwarded - and if the Inner formatter is nil, the built-in default formatter is used
+type TeeFormatter struct {
+	Inner   FrameFormatter
+	Sink    io.Writer
+	OnError func(err error)
+}
+
+var _ ExtendedFrameFormatter = (*TeeFormatter)(nil)
+var _ SourceFrameFormatter = (*TeeFormatter)(nil)
+var _ BoundaryFrameFormatter = (*TeeFormatter)(nil)
+var _ TruncatedFrameFormatter = (*TeeFormatter)(nil)
+var _ RepeatedFrameFormatter = (*TeeFormatter)(nil)
+
+func (tf *TeeFormatter) StartLine() string {
+	return tf.tee(tf.inner().StartLine())
+}
+
+func (tf *TeeFormatter) FrameLine(frame runtime.Frame) string {
+	return tf.tee(tf.inner().FrameLine(frame))
+}
+
+func (tf *TeeFormatter) FrameSeparator() string {
+	if xf, ok := tf.inner().(ExtendedFrameFormatter); ok {
+		return tf.tee(xf.FrameSeparator())
+	}
+	return ""
+}
+
+func (tf *TeeFormatter) EndLine() string {
+	if xf, ok := tf.inner().(ExtendedFrameFormatter); ok {
+		return tf.tee(xf.EndLine())
+	}
+	return ""
+}
+
+func (tf *TeeFormatter) SourceLine(source string) string {
+	if sf, ok := tf.inner().(SourceFrameFormatter); ok {
+		return tf.tee(sf.SourceLine(source))
+	}
+	return ""
+}
+
+func (tf *TeeFormatter) BoundaryLine() string {
+	if bf, ok := tf.inner().(BoundaryFrameFormatter); ok {
+		return tf.tee(bf.BoundaryLine())
+	}
+	return ""
+}
+
+func (tf *TeeFormatter) TruncatedLine() string {
+	if tr, ok := tf.inner().(TruncatedFrameFormatter); ok {
+		return tf.tee(tr.TruncatedLine())
+	}
+	return ""
+}
+
+func (tf *TeeFormatter) RepeatedFrameLine(frame runtime.Frame, repeats int) string {
+	if rf, ok := tf.inner().(RepeatedFrameFormatter); ok {
+		return tf.tee(rf.RepeatedFrameLine(frame, repeats))
+	}
+	return tf.FrameLine(frame)
+}
+
+// repeats determines whether the inner formatter supports collapsed frames (see repeatedFormatter)
+func (tf *TeeFormatter) repeats() bool {
+	_, ok := tf.inner().(RepeatedFrameFormatter)
+	return ok
+}
+
+func (tf *TeeFormatter) inner() FrameFormatter {
+	if tf.Inner == nil {
+		return &frameFormatter{}
+	}
+	return tf.Inner
+}
+
+func (tf *TeeFormatter) tee(s string) string {
+	if tf.Sink != nil && s != "" {
+		if _, err := io.WriteString(tf.Sink, s); err != nil && tf.OnError != nil {
+			tf.OnError(err)
+		}
+	}
+	return s
+}
+
+// Note on concurrency: the settings variables below are read (and may be written) without synchronization - so changing them
+// whilst other goroutines are creating or formatting errors is a data race. To change settings at runtime, use the accessor
+// functions instead (e.g. SetMaxStackDepth, SetCaptureStack, SetPackageFilter, SetDefaultPackageName and SetDefaultFrameFormatter)
+
 // DefaultPackageFilter is the default package filter used to determine which packages
 // are to be captured for the errors stack info
 var DefaultPackageFilter PackageFilter
@@ -53,9 +381,262 @@ var DefaultPackageFilter PackageFilter
 var DefaultPackageName string
 
 // MaxStackDepth is the maximum stack depth to capture
+//
+// A value of zero means unlimited (i.e. the entire stack is captured)
 var MaxStackDepth uint = 16
 
+// ExcludeSelf determines whether frames in the stackerr package itself (e.g. SafeGo) are excluded from the stack info
+// of errors - regardless of DefaultPackageFilter and DefaultPackageName
+//
+// This is disabled by default (for compatibility). Note: frames in test files of the stackerr package are never excluded
+var ExcludeSelf bool
+
+// CaptureStack determines whether call stack info is captured when errors are created
+//
+// When set to false, no stack info is captured at all (avoiding the cost of capture) - and StackError.StackInfo is always empty.
+// Note: this differs from setting DefaultFrameFormatter to nil - which only suppresses the output of the stack
+// info when formatting, but still incurs the cost of capture
+var CaptureStack = true
+
+// PreserveCauseStack determines whether wrapping a StackError (using Wrap or WrapWithOptions) preserves the stack info of the
+// wrapped error - rather than capturing new stack info at the point of wrapping
+//
+// When set to true, StackError.StackInfo of the wrapping error returns the stack info of the wrapped error (i.e. its origin).
+// Note: regardless of this setting, the stack info of the origin is always available using StackError.CauseStack
+var PreserveCauseStack bool
+
+// CaptureGoroutineID determines whether the id of the creating goroutine is captured when errors are created
+// (see StackError.Goroutine)
+//
+// This is disabled by default, as obtaining the goroutine id has an overhead
+var CaptureGoroutineID bool
+
+// CaptureTimestamp determines whether the time is captured when errors are created
+// (see StackError.Timestamp)
+//
+// This is disabled by default, to avoid the overhead (and so that errors are deterministic in tests)
+var CaptureTimestamp bool
+
+// LazyStack determines whether the call stack frames of errors are resolved lazily
+//
+// When set to true, only the raw program counters are captured when an error is created - and these are
+// resolved into StackInfo the first time the stack info is needed (e.g. StackError.StackInfo is called or the error is formatted)
+//
+// Note: with lazy resolution, DefaultPackageFilter and DefaultPackageName are applied at the point of resolution (rather than creation)
+var LazyStack bool
+
 // DefaultFrameFormatter is the formatter used to format call stack frames when formatting StackError
 //
 // If this is set to nil, no stack info is output when formatting StackError
 var DefaultFrameFormatter FrameFormatter = &frameFormatter{}
+
+// FormatFrameFilter, when set, determines which frames are output (those for which it returns true) when formatting
+// StackError (and by FormatStack) and when marshalling to JSON
+//
+// Unlike DefaultPackageFilter (which is applied when the stack is captured), the stack info of errors is not affected - so,
+// for example, all frames can be captured for programmatic inspection (see StackError.StackInfo) while standard library
+// frames are hidden from logs
+var FormatFrameFilter func(frame runtime.Frame) bool
+
+// StackOrderOuterFirst determines whether frames are output outermost first (as with, for example, Python tracebacks) when
+// formatting StackError (and by FormatStack) and when marshalling to JSON
+//
+// This is disabled by default (i.e. frames are output innermost first, the order in which they are captured).
+// Note: the stack info of errors is not affected - StackError.StackInfo always returns frames in capture order
+var StackOrderOuterFirst bool
+
+// CollapseRecursion determines whether consecutive identical frames (e.g. from recursion) are collapsed into a single
+// frame (annotated with a repeat count) when formatting StackError
+//
+// Frames are only collapsed when the FrameFormatter used implements RepeatedFrameFormatter (as all the built-in formatters do)
+//
+// Note: the stack info of errors is not affected (see StackInfo.Collapsed)
+var CollapseRecursion bool
+
+// CaptureSourceLines determines whether the source line text of each frame is captured when a StackError is created -
+// the captured text is included when formatting the error (for FrameFormatter implementations that also implement
+// SourceFrameFormatter - e.g. the default with %+v) and by StackError.SourceFrames
+//
+// This is disabled by default, as it reads source files from disk (and only works where the sources are present).
+// Source files are read the first time they are needed - and then cached.
+// Note: capturing the source lines resolves the stack frames at creation (even when LazyStack is set)
+var CaptureSourceLines bool
+
+// CaptureMessageArgs determines whether the args passed to Newf are retained by the error (see StackError.MessageTemplate)
+//
+// This is disabled by default, as retaining the args prevents them from being garbage collected for the lifetime of the error
+var CaptureMessageArgs bool
+
+// MergeCauseFields determines whether StackError.Fields includes the fields of the cause (when the cause is a StackError, e.g. created by Wrap)
+//
+// Fields are merged outer over inner - i.e. where the wrapping error and the wrapped error carry the same key, the field of the
+// wrapping error takes precedence. This is enabled by default; when disabled, Fields returns only the fields attached to the error itself.
+// Note: formatting (and marshalling) always outputs the fields of each error with that error - only Fields returns the merged view
+var MergeCauseFields = true
+
+// FingerprintDepth is the number of (top) frames used to derive the fingerprint of errors (see StackError.Fingerprint)
+var FingerprintDepth = 3
+
+// UsePool determines whether errors are allocated from a pool (see Release)
+//
+// This can reduce allocations (and GC pressure) under high error rates - but only where errors are explicitly
+// released once they are no longer needed
+var UsePool bool
+
+// FormatBufferSize is the initial capacity (in bytes) of the pooled buffers used to build strings (e.g. by FormatStack,
+// StackInfo.String and StackError.Logfmt)
+//
+// Pre-sizing reduces reallocations when formatting large stacks repeatedly (e.g. in logging hot paths) - a value of zero (or less)
+// disables the pooled buffers
+var FormatBufferSize = 512
+
+// CauseSeparator is the separator written between the message of an error and the message of its cause
+// when formatting StackError (with %v or %+v) and by StackError.FullMessage
+var CauseSeparator = ": "
+
+// MaxCauseDepth is the maximum number of cause levels descended when formatting StackError with %+v (and when marshalling to JSON)
+//
+// Causes beyond the maximum depth are output as "... (N more)" - a value of zero (the default) means unlimited
+var MaxCauseDepth int
+
+// MaxMessageLength is the maximum length (in bytes) of error messages - longer messages are truncated (with a "..." suffix)
+// when errors are created
+//
+// The truncated message, including the "..." suffix, never exceeds MaxMessageLength and is never truncated part way
+// through a multibyte character. A value of zero (the default) means unlimited
+var MaxMessageLength int
+
+// OnNew, when set, is called whenever a new StackError is created (e.g. by New, Newf, Wrap etc.)
+//
+// This can be used, for example, to count errors or sample stacks without changing call sites.
+// Note: OnNew is called synchronously on the goroutine creating the error - so it should be fast and non-blocking
+var OnNew func(err StackError)
+
+// OnErrorMetric, when set, is called whenever a new StackError is created (e.g. by New, Newf, Wrap etc.) with the
+// fingerprint of the error (see StackError.Fingerprint)
+//
+// This allows a metrics layer to count errors by error site (e.g. for a dashboard of "top error sites") without changing call sites.
+// As the fingerprint is derived at creation, it does not include properties set afterward by builder methods (e.g. the code).
+// Note: OnErrorMetric is called synchronously on the goroutine creating the error - and deriving the fingerprint hashes the
+// message and top frames (resolving the stack if LazyStack is set) - so it adds a cost to every error created and the hook
+// itself should be fast and non-blocking. It is nil (off) by default
+var OnErrorMetric func(fingerprint string, err StackError)
+
+// PanicOnNewMatching, when set, is called whenever a new StackError is created - and if it returns true, panics
+// immediately (with the new error as the panic value)
+//
+// This is a development-only debugging aid - e.g. to break into a debugger at the exact site at which an error
+// matching some predicate is created. It is nil (off) by default and should never be set in production
+var PanicOnNewMatching func(err StackError) bool
+
+// TrimFilePrefix, when set, is trimmed from the start of frame file paths when output (see FrameFile)
+var TrimFilePrefix string
+
+// TrimToModule determines whether frame file paths are trimmed to be relative to the module containing
+// the frame's package when output (see FrameFile)
+//
+// e.g. "/home/me/src/myapp/handlers/users.go" (in module "github.com/me/myapp") is output as "handlers/users.go"
+//
+// If the module of the frame's package cannot be determined (e.g. standard library packages), TrimFilePrefix is used
+var TrimToModule bool
+
+// FrameFormatterResolver, when set, is used to resolve the FrameFormatter used when formatting StackError
+// for a given format verb (and whether the '+' flag is present)
+//
+// If FrameFormatterResolver is nil or returns nil, formatting with %+v uses DefaultFrameFormatter and
+// all other verbs output no stack info
+var FrameFormatterResolver func(verb rune, plus bool) FrameFormatter
+
+var settingsMu sync.RWMutex
+
+// SetPackageFilter sets the DefaultPackageFilter (safe for concurrent use)
+func SetPackageFilter(f PackageFilter) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	DefaultPackageFilter = f
+}
+
+// DefaultPackageFilterValue returns the DefaultPackageFilter (safe for concurrent use)
+func DefaultPackageFilterValue() PackageFilter {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return DefaultPackageFilter
+}
+
+// SetDefaultPackageName sets the DefaultPackageName (safe for concurrent use)
+func SetDefaultPackageName(pkg string) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	DefaultPackageName = pkg
+}
+
+// SetDefaultPackageFromCaller sets the DefaultPackageName to the short package name of the function that called it
+// (safe for concurrent use) - so that, for example, a program's main package can self-configure
+//
+// The frame used is always the immediate caller - so when called from an init func, the package is that of the init func
+// (e.g. "main" for main.init.0) and when called from a test, the package is that of the test (e.g. "foo" for an
+// internal test in package foo, or "foo_test" for an external test package)
+func SetDefaultPackageFromCaller() {
+	if pc, _, _, ok := runtime.Caller(1); ok {
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			_, short := packageFromFunction(fn.Name())
+			SetDefaultPackageName(short)
+		}
+	}
+}
+
+// DefaultPackageNameValue returns the DefaultPackageName (safe for concurrent use)
+func DefaultPackageNameValue() string {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return DefaultPackageName
+}
+
+// SetMaxStackDepth sets the MaxStackDepth (safe for concurrent use)
+func SetMaxStackDepth(depth uint) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	MaxStackDepth = depth
+}
+
+// MaxStackDepthValue returns the MaxStackDepth (safe for concurrent use)
+func MaxStackDepthValue() uint {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return MaxStackDepth
+}
+
+// SetCaptureStack sets CaptureStack (safe for concurrent use)
+func SetCaptureStack(capture bool) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	CaptureStack = capture
+}
+
+// CaptureStackValue returns CaptureStack (safe for concurrent use)
+func CaptureStackValue() bool {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return CaptureStack
+}
+
+// SetDefaultFrameFormatter sets the DefaultFrameFormatter (safe for concurrent use)
+func SetDefaultFrameFormatter(f FrameFormatter) {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	DefaultFrameFormatter = f
+}
+
+// DefaultFrameFormatterValue returns the DefaultFrameFormatter (safe for concurrent use)
+func DefaultFrameFormatterValue() FrameFormatter {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return DefaultFrameFormatter
+}
+
+// packageSettings returns the DefaultPackageFilter and DefaultPackageName (read together)
+func packageSettings() (PackageFilter, string) {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return DefaultPackageFilter, DefaultPackageName
+}
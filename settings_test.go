@@ -0,0 +1,492 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestError_Format_FrameFormatterResolver(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	FrameFormatterResolver = func(verb rune, plus bool) FrameFormatter {
+		switch {
+		case verb == 'v' && !plus:
+			return &testCompactFormatter{}
+		case verb == 'd':
+			return &testCompactFormatter{}
+		}
+		return nil
+	}
+	defer func() {
+		DefaultPackageName = ""
+		FrameFormatterResolver = nil
+	}()
+	e := New("fooey").WithCause(errors.New("cause"))
+	require.Equal(t, "fooey: cause [TestError_Format_FrameFormatterResolver]", fmt.Sprintf("%v", e))
+	require.Equal(t, "fooey: cause [TestError_Format_FrameFormatterResolver]", fmt.Sprintf("%d", e))
+	out := fmt.Sprintf("%+v", e)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, "fooey: cause", lines[0])
+	require.Equal(t, "Stack:", lines[1])
+	require.Equal(t, "fooey", fmt.Sprintf("%s", e))
+	require.Equal(t, "%!x(stackerr.err)", fmt.Sprintf("%x", e))
+}
+
+type testCompactFormatter struct{}
+
+func (tf *testCompactFormatter) StartLine() string {
+	return " "
+}
+
+func (tf *testCompactFormatter) FrameLine(frame runtime.Frame) string {
+	return "[" + frame.Function[strings.LastIndexByte(frame.Function, '.')+1:] + "]"
+}
+
+func TestSetDefaultPackageFilterRegexp(t *testing.T) {
+	defer func() {
+		DefaultPackageFilter = nil
+	}()
+	err := SetDefaultPackageFilterRegexp(`^github\.com/go-andiamo/`)
+	require.NoError(t, err)
+	require.NotNil(t, DefaultPackageFilter)
+	require.True(t, DefaultPackageFilter.Include("github.com/go-andiamo/stackerr"))
+	require.True(t, DefaultPackageFilter.Include("github.com/go-andiamo/other"))
+	require.False(t, DefaultPackageFilter.Include("github.com/someone/stackerr"))
+	require.False(t, DefaultPackageFilter.Include("testing"))
+	require.False(t, DefaultPackageFilter.Include("runtime"))
+
+	e := New("fooey")
+	si := e.StackInfo()
+	require.Len(t, si, 1)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestSetDefaultPackageFilterRegexp", si[0].Function)
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		before := DefaultPackageFilter
+		err := SetDefaultPackageFilterRegexp(`^github.com/(`)
+		require.Error(t, err)
+		require.Equal(t, before, DefaultPackageFilter)
+	})
+}
+
+func TestCompactFrameFormatter(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	DefaultFrameFormatter = &CompactFrameFormatter{}
+	TrimToModule = true
+	defer func() {
+		DefaultPackageName = ""
+		DefaultFrameFormatter = &frameFormatter{}
+		TrimToModule = false
+	}()
+	e := recurse(1, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(3))
+	})
+	si := e.StackInfo()
+	require.Len(t, si, 3)
+	out := fmt.Sprintf("%+v", e)
+	require.Equal(t, fmt.Sprintf("fooey [stack: %s(settings_test.go:%d) <- %s(options_test.go:%d) <- %s(options_test.go:%d)]",
+		si[0].Function, si[0].Line,
+		si[1].Function, si[1].Line,
+		si[2].Function, si[2].Line), out)
+	require.NotContains(t, out, "\n")
+
+	t.Run("collapsed", func(t *testing.T) {
+		CollapseRecursion = true
+		defer func() {
+			CollapseRecursion = false
+		}()
+		out := fmt.Sprintf("%+v", e)
+		require.Equal(t, fmt.Sprintf("fooey [stack: %s(settings_test.go:%d) <- %s(options_test.go:%d) (x2)]",
+			si[0].Function, si[0].Line,
+			si[1].Function, si[1].Line), out)
+	})
+}
+
+func TestJSONFrameFormatter(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	DefaultFrameFormatter = &JSONFrameFormatter{}
+	defer func() {
+		DefaultPackageName = ""
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := recurse(1, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(3))
+	}).WithCause(errors.New(`cause with "quotes"`))
+	si := e.StackInfo()
+	require.Len(t, si, 3)
+	out := fmt.Sprintf("%+v", e)
+	lines := strings.Split(out, "\n")
+	require.Len(t, lines, 2)
+	require.Equal(t, `fooey: cause with "quotes"`, lines[0])
+	var frames []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &frames))
+	require.Len(t, frames, 3)
+	for i, fr := range frames {
+		require.Equal(t, si[i].Function, fr["func"])
+		require.Equal(t, si[i].File, fr["file"])
+		require.Equal(t, float64(si[i].Line), fr["line"])
+	}
+
+	t.Run("single frame", func(t *testing.T) {
+		e := NewWithOptions("fooey", WithMaxDepth(1))
+		lines := strings.Split(fmt.Sprintf("%+v", e), "\n")
+		require.Len(t, lines, 2)
+		var frames []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &frames))
+		require.Len(t, frames, 1)
+	})
+	t.Run("collapsed", func(t *testing.T) {
+		CollapseRecursion = true
+		defer func() {
+			CollapseRecursion = false
+		}()
+		e := recurse(4, func() StackError {
+			return NewWithOptions("fooey", WithMaxDepth(0))
+		})
+		si := e.StackInfo()
+		lines := strings.Split(fmt.Sprintf("%+v", e), "\n")
+		require.Len(t, lines, 2)
+		var frames []map[string]any
+		require.NoError(t, json.Unmarshal([]byte(lines[1]), &frames))
+		require.Len(t, frames, 3)
+		_, ok := frames[0]["repeats"]
+		require.False(t, ok)
+		require.Equal(t, si[1].Function, frames[1]["func"])
+		require.Equal(t, float64(5), frames[1]["repeats"])
+		_, ok = frames[2]["repeats"]
+		require.False(t, ok)
+	})
+}
+
+func TestCollapseRecursion_NotSupported(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	DefaultFrameFormatter = &testCompactFormatter{}
+	CollapseRecursion = true
+	defer func() {
+		DefaultPackageName = ""
+		DefaultFrameFormatter = &frameFormatter{}
+		CollapseRecursion = false
+	}()
+	e := recurse(3, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(0))
+	})
+	// not collapsed (as the formatter does not implement RepeatedFrameFormatter)...
+	require.Equal(t, 4, strings.Count(fmt.Sprintf("%+v", e), "[recurse]"))
+	require.NotContains(t, fmt.Sprintf("%+v", e), "(x")
+
+	DefaultFrameFormatter = &TeeFormatter{Inner: &testCompactFormatter{}}
+	require.Equal(t, 4, strings.Count(fmt.Sprintf("%+v", e), "[recurse]"))
+	DefaultFrameFormatter = &TeeFormatter{}
+	require.Contains(t, fmt.Sprintf("%+v", e), ".recurse:"+strconv.Itoa(e.StackInfo()[1].Line)+" (x4)")
+}
+
+func TestSettings_Accessors(t *testing.T) {
+	defer func() {
+		SetMaxStackDepth(16)
+		SetCaptureStack(true)
+		SetPackageFilter(nil)
+		SetDefaultPackageName("")
+		SetDefaultFrameFormatter(&frameFormatter{})
+	}()
+	SetMaxStackDepth(32)
+	require.Equal(t, uint(32), MaxStackDepth)
+	require.Equal(t, uint(32), MaxStackDepthValue())
+	SetCaptureStack(false)
+	require.False(t, CaptureStack)
+	require.False(t, CaptureStackValue())
+	f := PrefixFilter("github.com/go-andiamo/")
+	SetPackageFilter(f)
+	require.Equal(t, f, DefaultPackageFilter)
+	require.Equal(t, f, DefaultPackageFilterValue())
+	SetDefaultPackageName("stackerr")
+	require.Equal(t, "stackerr", DefaultPackageName)
+	require.Equal(t, "stackerr", DefaultPackageNameValue())
+	ff := &CompactFrameFormatter{}
+	SetDefaultFrameFormatter(ff)
+	require.Equal(t, ff, DefaultFrameFormatter)
+	require.Equal(t, ff, DefaultFrameFormatterValue())
+}
+
+func TestSettings_Concurrent(t *testing.T) {
+	defer func() {
+		SetMaxStackDepth(16)
+		SetCaptureStack(true)
+		SetPackageFilter(nil)
+		SetDefaultPackageName("")
+		SetDefaultFrameFormatter(&frameFormatter{})
+	}()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				SetMaxStackDepth(uint(8 + j%16))
+				SetCaptureStack(j%3 != 0)
+				SetDefaultPackageName([]string{"", "stackerr"}[j%2])
+				SetDefaultPackageFilterPrefix("github.com/go-andiamo/")
+				SetPackageFilter(nil)
+				SetDefaultFrameFormatter([]FrameFormatter{&frameFormatter{}, &CompactFrameFormatter{}, nil}[(i+j)%3])
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				e := Wrap(New("fooey"), "wrapped")
+				_ = fmt.Sprintf("%+v", e)
+				_ = e.StackTrace()
+				_, _ = json.Marshal(e)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSetDefaultPackageFromCaller(t *testing.T) {
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	SetDefaultPackageFromCaller()
+	require.Equal(t, "stackerr", DefaultPackageNameValue())
+
+	DefaultPackageName = ""
+	func() {
+		SetDefaultPackageFromCaller()
+	}()
+	require.Equal(t, "stackerr", DefaultPackageNameValue())
+	e := New("fooey")
+	require.NotEmpty(t, e.StackInfo())
+	for _, fr := range e.StackInfo() {
+		require.True(t, strings.HasPrefix(fr.Function, "github.com/go-andiamo/stackerr."))
+	}
+}
+
+func TestSetDefaultPackageFilterToModule(t *testing.T) {
+	defer func() {
+		DefaultPackageFilter = nil
+		readBuildInfo = debug.ReadBuildInfo
+	}()
+	err := SetDefaultPackageFilterToModule()
+	require.NoError(t, err)
+	f := DefaultPackageFilterValue()
+	require.NotNil(t, f)
+	require.True(t, f.Include("github.com/go-andiamo/stackerr"))
+	require.True(t, f.Include("github.com/go-andiamo/stackerr/sub"))
+	require.False(t, f.Include("github.com/go-andiamo/stackerrs"))
+	require.False(t, f.Include("runtime"))
+	require.False(t, f.Include("testing"))
+	e := New("fooey")
+	require.NotEmpty(t, e.StackInfo())
+	for _, fr := range e.StackInfo() {
+		require.True(t, strings.HasPrefix(fr.Function, "github.com/go-andiamo/stackerr."))
+	}
+
+	t.Run("custom module", func(t *testing.T) {
+		readBuildInfo = func() (*debug.BuildInfo, bool) {
+			return &debug.BuildInfo{Main: debug.Module{Path: "example.com/foo"}}, true
+		}
+		require.NoError(t, SetDefaultPackageFilterToModule())
+		require.True(t, DefaultPackageFilterValue().Include("example.com/foo/bar"))
+		require.False(t, DefaultPackageFilterValue().Include("github.com/go-andiamo/stackerr"))
+	})
+	t.Run("no build info", func(t *testing.T) {
+		DefaultPackageFilter = nil
+		readBuildInfo = func() (*debug.BuildInfo, bool) {
+			return nil, false
+		}
+		require.ErrorIs(t, SetDefaultPackageFilterToModule(), ErrNoBuildInfo)
+		require.Nil(t, DefaultPackageFilterValue())
+	})
+	t.Run("no main module path", func(t *testing.T) {
+		readBuildInfo = func() (*debug.BuildInfo, bool) {
+			return &debug.BuildInfo{}, true
+		}
+		require.ErrorIs(t, SetDefaultPackageFilterToModule(), ErrNoBuildInfo)
+		require.Nil(t, DefaultPackageFilterValue())
+	})
+}
+
+func TestTeeFormatter(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey")
+	si := e.StackInfo()
+	require.NotEmpty(t, si)
+
+	t.Run("default inner", func(t *testing.T) {
+		sink := &strings.Builder{}
+		ff := &TeeFormatter{Inner: &frameFormatter{}, Sink: sink}
+		out := fmt.Sprintf("%+v", e)
+		DefaultFrameFormatter = ff
+		defer func() {
+			DefaultFrameFormatter = &frameFormatter{}
+		}()
+		require.Equal(t, out, fmt.Sprintf("%+v", e))
+		require.Equal(t, fmt.Sprintf("\nStack:\n\t%s:%d", si[0].Function, si[0].Line), sink.String())
+	})
+	t.Run("extended inner", func(t *testing.T) {
+		sink := &strings.Builder{}
+		inner := &CompactFrameFormatter{}
+		ff := &TeeFormatter{Inner: inner, Sink: sink}
+		require.Equal(t, inner.StartLine(), ff.StartLine())
+		require.Equal(t, inner.FrameLine(si[0]), ff.FrameLine(si[0]))
+		require.Equal(t, inner.FrameSeparator(), ff.FrameSeparator())
+		require.Equal(t, inner.EndLine(), ff.EndLine())
+		require.Equal(t, inner.StartLine()+inner.FrameLine(si[0])+inner.FrameSeparator()+inner.EndLine(), sink.String())
+	})
+	t.Run("extended inner formatted", func(t *testing.T) {
+		sink := &strings.Builder{}
+		DefaultFrameFormatter = &TeeFormatter{Inner: &CompactFrameFormatter{}, Sink: sink}
+		defer func() {
+			DefaultFrameFormatter = &frameFormatter{}
+		}()
+		e := recurse(2, func() StackError {
+			return NewWithOptions("fooey", WithMaxDepth(3))
+		})
+		out := fmt.Sprintf("%+v", e)
+		require.Equal(t, "fooey"+sink.String(), out)
+		require.Equal(t, 2, strings.Count(sink.String(), " <- "))
+		require.True(t, strings.HasPrefix(sink.String(), " [stack: "))
+		require.True(t, strings.HasSuffix(sink.String(), "]"))
+	})
+	t.Run("sink error", func(t *testing.T) {
+		var errs []error
+		ff := &TeeFormatter{Inner: &frameFormatter{}, Sink: &failingWriter{}, OnError: func(err error) {
+			errs = append(errs, err)
+		}}
+		require.Equal(t, "\nStack:", ff.StartLine())
+		require.Equal(t, "", ff.FrameSeparator())
+		require.Len(t, errs, 1)
+		ff.OnError = nil
+		require.Equal(t, "\nStack:", ff.StartLine())
+	})
+	t.Run("nil sink", func(t *testing.T) {
+		ff := &TeeFormatter{Inner: &frameFormatter{}}
+		require.Equal(t, (&frameFormatter{}).FrameLine(si[0]), ff.FrameLine(si[0]))
+	})
+	t.Run("nil inner", func(t *testing.T) {
+		sink := &strings.Builder{}
+		ff := &TeeFormatter{Sink: sink}
+		require.Equal(t, (&frameFormatter{}).FrameLine(si[0]), ff.FrameLine(si[0]))
+		require.Equal(t, "\n\t... (truncated)", ff.TruncatedLine())
+		require.Equal(t, (&frameFormatter{}).FrameLine(si[0])+"\n\t... (truncated)", sink.String())
+	})
+	t.Run("forwards extensions", func(t *testing.T) {
+		sink := &strings.Builder{}
+		DefaultFrameFormatter = &TeeFormatter{Inner: &frameFormatter{}, Sink: sink}
+		CaptureSourceLines = true
+		defer func() {
+			DefaultFrameFormatter = &frameFormatter{}
+			CaptureSourceLines = false
+		}()
+		e := recurse(10, func() StackError {
+			return NewWithOptions("deep", WithMaxDepth(3))
+		}).AppendStack(New("other"))
+		out := fmt.Sprintf("%+v", e)
+		require.Contains(t, out, "\n\t--- appended stack ---")
+		require.Contains(t, out, "\n\t... (truncated)")
+		require.Contains(t, out, "\n\t\treturn NewWithOptions(\"deep\", WithMaxDepth(3))")
+		require.True(t, strings.HasSuffix(out, sink.String()))
+
+		// not forwarded if the inner formatter does not implement them...
+		ff := &TeeFormatter{Inner: &CompactFrameFormatter{}}
+		require.Equal(t, "", ff.TruncatedLine())
+		require.Equal(t, "", ff.BoundaryLine())
+		require.Equal(t, "", ff.SourceLine("src"))
+		require.Equal(t, "", (&TeeFormatter{Inner: &RelativeFrameFormatter{}}).FrameSeparator())
+	})
+}
+
+type failingWriter struct{}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestRelativeFrameFormatter(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	DefaultFrameFormatter = &RelativeFrameFormatter{}
+	defer func() {
+		DefaultPackageName = ""
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	mk := func() StackError {
+		return recurse(1, func() StackError {
+			return NewWithOptions("fooey", WithMaxDepth(3))
+		})
+	}
+	e := mk()
+	si := e.StackInfo()
+	require.Len(t, si, 3)
+	out := fmt.Sprintf("%+v", e)
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\tstackerr.TestRelativeFrameFormatter.func2.func1:%d\n\tstackerr.recurse:%d\n\tstackerr.recurse:%d\n\t... (truncated)",
+		si[0].Line, si[1].Line, si[2].Line), out)
+	require.NotContains(t, out, "/")
+	require.NotContains(t, out, "\\")
+	require.NotContains(t, out, ".go")
+	// stable across runs...
+	require.Equal(t, out, fmt.Sprintf("%+v", mk()))
+}
+
+func TestRelativeFunction(t *testing.T) {
+	testCases := map[string]string{
+		"main.main":                           "main.main",
+		"github.com/me/pkg.Func":              "pkg.Func",
+		"github.com/me/pkg.(*T).M":            "pkg.(*T).M",
+		"github.com/me/pkg.Func.func1":        "pkg.Func.func1",
+		"github.com/me/pkg.Func[...]":         "pkg.Func[...]",
+		"gopkg.in/yaml%2ev3.Unmarshal":        "yaml%2ev3.Unmarshal",
+		"github.com/me/pkg.Func[a/b.T].func1": "pkg.Func[a/b.T].func1",
+	}
+	for name, expect := range testCases {
+		require.Equal(t, expect, relativeFunction(name))
+	}
+}
+
+func TestError_WithFrameFormatter(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := NewWithOptions("fooey", WithMaxDepth(1))
+	si := e.StackInfo()
+	require.Len(t, si, 1)
+
+	compact := e.WithFrameFormatter(&CompactFrameFormatter{})
+	require.Equal(t, fmt.Sprintf("fooey [stack: %s(%s:%d)]", si[0].Function, FrameFile(si[0]), si[0].Line), fmt.Sprintf("%+v", compact))
+	jsonFrames := e.WithFrameFormatter(&JSONFrameFormatter{})
+	require.Equal(t, fmt.Sprintf(`fooey
+[{"func":"%s","file":"%s","line":%d}]`, si[0].Function, FrameFile(si[0]), si[0].Line), fmt.Sprintf("%+v", jsonFrames))
+	// original is unaffected...
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\t%s:%d\n\t... (truncated)", si[0].Function, si[0].Line), fmt.Sprintf("%+v", e))
+	// nil reverts to default...
+	require.Equal(t, fmt.Sprintf("%+v", e), fmt.Sprintf("%+v", compact.WithFrameFormatter(nil)))
+
+	t.Run("overrides globals", func(t *testing.T) {
+		DefaultFrameFormatter = nil
+		FrameFormatterResolver = func(verb rune, plus bool) FrameFormatter {
+			return &JSONFrameFormatter{}
+		}
+		defer func() {
+			DefaultFrameFormatter = &frameFormatter{}
+			FrameFormatterResolver = nil
+		}()
+		require.True(t, strings.HasPrefix(fmt.Sprintf("%+v", e), "fooey\n[{"))
+		require.Equal(t, fmt.Sprintf("fooey [stack: %s(%s:%d)]", si[0].Function, FrameFile(si[0]), si[0].Line), fmt.Sprintf("%+v", compact))
+	})
+	t.Run("wrapped", func(t *testing.T) {
+		w := Wrap(compact, "outer")
+		out := fmt.Sprintf("%+v", w)
+		require.True(t, strings.HasPrefix(out, "outer: fooey [stack: "))
+		require.Contains(t, out, "\nStack:\n\t")
+	})
+}
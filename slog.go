@@ -0,0 +1,37 @@
+package stackerr
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+var _ slog.LogValuer = (*err)(nil)
+
+// LogValue implements slog.LogValuer
+//
+// The error is logged as a group containing "msg", and (when present) "cause", "code", "severity", "fields" and "stack"
+func (e *err) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, 6)
+	attrs = append(attrs, slog.String("msg", e.text()))
+	if e.cause != nil {
+		attrs = append(attrs, slog.String("cause", fullMessage(e.cause)))
+	}
+	if e.code != "" {
+		attrs = append(attrs, slog.String("code", e.code))
+	}
+	if e.severity != "" {
+		attrs = append(attrs, slog.String("severity", string(e.severity)))
+	}
+	if len(e.fields) > 0 {
+		fields := make([]any, 0, len(e.fields))
+		for _, k := range slices.Sorted(maps.Keys(e.fields)) {
+			fields = append(fields, slog.Any(k, e.fields[k]))
+		}
+		attrs = append(attrs, slog.Group("fields", fields...))
+	}
+	if st := e.StackTrace(); len(st) > 0 {
+		attrs = append(attrs, slog.Any("stack", st))
+	}
+	return slog.GroupValue(attrs...)
+}
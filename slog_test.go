@@ -0,0 +1,99 @@
+package stackerr
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+)
+
+func TestError_LogValue(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	h := &testSlogHandler{}
+	logger := slog.New(h)
+
+	e := Wrap(errors.New("cause"), "fooey").
+		WithCode("CODE").
+		WithSeverity(SeverityWarn).
+		WithFields(map[string]any{"foo": 1, "bar": "baz"})
+	logger.Error("failed", "err", e)
+	require.Len(t, h.records, 1)
+	attrs := h.attrs(0)
+	require.Len(t, attrs, 1)
+	require.Equal(t, "err", attrs[0].Key)
+	require.Equal(t, slog.KindGroup, attrs[0].Value.Kind())
+	group := attrs[0].Value.Group()
+	require.Len(t, group, 6)
+	require.Equal(t, "msg", group[0].Key)
+	require.Equal(t, "fooey", group[0].Value.String())
+	require.Equal(t, "cause", group[1].Key)
+	require.Equal(t, "cause", group[1].Value.String())
+	require.Equal(t, "code", group[2].Key)
+	require.Equal(t, "CODE", group[2].Value.String())
+	require.Equal(t, "severity", group[3].Key)
+	require.Equal(t, "warn", group[3].Value.String())
+	require.Equal(t, "fields", group[4].Key)
+	fields := group[4].Value.Group()
+	require.Len(t, fields, 2)
+	require.Equal(t, "bar", fields[0].Key)
+	require.Equal(t, "baz", fields[0].Value.String())
+	require.Equal(t, "foo", fields[1].Key)
+	require.Equal(t, int64(1), fields[1].Value.Int64())
+	require.Equal(t, "stack", group[5].Key)
+	stack, ok := group[5].Value.Any().([]string)
+	require.True(t, ok)
+	require.Len(t, stack, 1)
+	require.Contains(t, stack[0], "TestError_LogValue")
+}
+
+func TestError_LogValue_Minimal(t *testing.T) {
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	h := &testSlogHandler{}
+	slog.New(h).Error("failed", "err", New("fooey"))
+	attrs := h.attrs(0)
+	require.Len(t, attrs, 1)
+	group := attrs[0].Value.Group()
+	require.Len(t, group, 1)
+	require.Equal(t, "msg", group[0].Key)
+	require.Equal(t, "fooey", group[0].Value.String())
+}
+
+type testSlogHandler struct {
+	records []slog.Record
+}
+
+var _ slog.Handler = (*testSlogHandler)(nil)
+
+func (h *testSlogHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *testSlogHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *testSlogHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *testSlogHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func (h *testSlogHandler) attrs(i int) []slog.Attr {
+	result := make([]slog.Attr, 0)
+	h.records[i].Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		result = append(result, a)
+		return true
+	})
+	return result
+}
@@ -0,0 +1,98 @@
+package stackerr
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// SourceFrame is a stack frame annotated with the text of its source line (see CaptureSourceLines and StackError.SourceFrames)
+type SourceFrame struct {
+	runtime.Frame
+	// Source is the (whitespace trimmed) text of the source line - or empty string if not available
+	Source string
+}
+
+// SourceFrameFormatter is an optional extension to FrameFormatter
+//
+// When the FrameFormatter used also implements SourceFrameFormatter, the SourceLine is written after each frame line
+// for which the source line text was captured (see CaptureSourceLines)
+type SourceFrameFormatter interface {
+	FrameFormatter
+	SourceLine(source string) string
+}
+
+func (e *err) SourceFrames() []SourceFrame {
+	si := e.StackInfo()
+	result := make([]SourceFrame, 0, len(si))
+	for _, fr := range si {
+		result = append(result, SourceFrame{Frame: fr, Source: e.stack.sources[sourcePos{file: fr.File, line: fr.Line}]})
+	}
+	return result
+}
+
+// sourcePos is the position (file and line) of a frame in the source
+type sourcePos struct {
+	file string
+	line int
+}
+
+// captureSources captures the source line text of the frames (see CaptureSourceLines) - frames for which the source
+// line text is not available are omitted
+func captureSources(si StackInfo) map[sourcePos]string {
+	var result map[sourcePos]string
+	for _, fr := range si {
+		if src := sourceLine(fr.File, fr.Line); src != "" {
+			if result == nil {
+				result = make(map[sourcePos]string, len(si))
+			}
+			result[sourcePos{file: fr.File, line: fr.Line}] = src
+		}
+	}
+	return result
+}
+
+// mergeSources merges captured source lines (e.g. when appending stacks) - the captured maps are never modified
+func mergeSources(a, b map[sourcePos]string) map[sourcePos]string {
+	if len(a) == 0 {
+		return b
+	} else if len(b) == 0 {
+		return a
+	}
+	result := maps.Clone(a)
+	maps.Copy(result, b)
+	return result
+}
+
+// sourceFiles is the cache of source file lines (by file path) - files that cannot be read are cached as nil
+var sourceFiles sync.Map
+
+// sourceLine returns the (whitespace trimmed) text of the line in the source file - or empty string if not available
+func sourceLine(file string, line int) string {
+	if file == "" || line < 1 {
+		return ""
+	}
+	lines, ok := sourceFiles.Load(file)
+	if !ok {
+		var read [][]byte
+		if data, err := os.ReadFile(file); err == nil {
+			read = bytes.Split(data, []byte("\n"))
+		}
+		lines, _ = sourceFiles.LoadOrStore(file, read)
+	}
+	if ls := lines.([][]byte); line <= len(ls) {
+		return string(bytes.TrimSpace(ls[line-1]))
+	}
+	return ""
+}
+
+func writeSourceLine(w io.Writer, sf SourceFrameFormatter, sources map[sourcePos]string, frame runtime.Frame) {
+	if sf != nil {
+		if src := sources[sourcePos{file: frame.File, line: frame.Line}]; src != "" {
+			_, _ = io.WriteString(w, sf.SourceLine(src))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package stackerr
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestError_SourceFrames(t *testing.T) {
+	e := New("fooey") // source line marker
+	sfs := e.SourceFrames()
+	require.Len(t, sfs, len(e.StackInfo()))
+	require.Equal(t, e.StackInfo()[0], sfs[0].Frame)
+	require.Empty(t, sfs[0].Source)
+
+	CaptureSourceLines = true
+	defer func() {
+		CaptureSourceLines = false
+	}()
+	// source lines are captured at creation...
+	require.Empty(t, e.SourceFrames()[0].Source)
+	e = New("fooey") // source line marker
+	CaptureSourceLines = false
+	sfs = e.SourceFrames()
+	require.Equal(t, `e = New("fooey") // source line marker`, sfs[0].Source)
+
+	CaptureSourceLines = true
+	LazyStack = true
+	defer func() {
+		LazyStack = false
+	}()
+	e = New("fooey") // lazy source line marker
+	LazyStack = false
+	CaptureSourceLines = false
+	require.Equal(t, `e = New("fooey") // lazy source line marker`, e.SourceFrames()[0].Source)
+
+	// captured source lines are retained by appended stacks...
+	CaptureSourceLines = true
+	other := New("other") // appended source line marker
+	CaptureSourceLines = false
+	sfs = e.AppendStack(other).SourceFrames()
+	require.Equal(t, `e = New("fooey") // lazy source line marker`, sfs[0].Source)
+	require.Equal(t, `other := New("other") // appended source line marker`, sfs[len(e.StackInfo())].Source)
+	sfs = e.WithSyntheticFrame("synthetic", "synthetic.go", 1).SourceFrames()
+	require.Empty(t, sfs[0].Source)
+	require.Equal(t, `e = New("fooey") // lazy source line marker`, sfs[1].Source)
+}
+
+func TestError_Format_SourceLines(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey") // another source line marker
+	fr, _ := e.CallerFrame()
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\t%s:%d", fr.Function, fr.Line), fmt.Sprintf("%+v", e))
+
+	CaptureSourceLines = true
+	defer func() {
+		CaptureSourceLines = false
+	}()
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\t%s:%d", fr.Function, fr.Line), fmt.Sprintf("%+v", e))
+	e = New("fooey") // another source line marker
+	fr, _ = e.CallerFrame()
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\t%s:%d\n\t\te = New(\"fooey\") // another source line marker", fr.Function, fr.Line), fmt.Sprintf("%+v", e))
+
+	// formatters not implementing SourceFrameFormatter are unaffected...
+	DefaultFrameFormatter = &CompactFrameFormatter{}
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.False(t, strings.Contains(fmt.Sprintf("%+v", e), "marker"))
+}
+
+func TestSourceLine(t *testing.T) {
+	require.Equal(t, "", sourceLine("", 1))
+	require.Equal(t, "", sourceLine("does-not-exist.go", 1))
+	require.Equal(t, "", sourceLine("does-not-exist.go", 1))
+	e := New("fooey")
+	fr, _ := e.CallerFrame()
+	require.Equal(t, "package stackerr", sourceLine(fr.File, 1))
+	require.Equal(t, "", sourceLine(fr.File, 0))
+	require.Equal(t, "", sourceLine(fr.File, 100000))
+}
@@ -0,0 +1,297 @@
+package stackerr
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// callStack is the captured call stack of an error
+//
+// when LazyStack is enabled, the frames are not resolved until first needed
+type callStack struct {
+	frames StackInfo
+	lazy   *lazyFrames
+	// truncated indicates the call stack was deeper than the max depth captured
+	truncated bool
+	// sources is the source line text of the frames captured when the error was created (see CaptureSourceLines)
+	sources map[sourcePos]string
+}
+
+func (cs callStack) resolved() StackInfo {
+	if cs.lazy != nil {
+		return cs.lazy.resolve()
+	}
+	return cs.frames
+}
+
+type lazyFrames struct {
+	once   sync.Once
+	pcs    []uintptr
+	opts   options
+	frames StackInfo
+}
+
+func (lf *lazyFrames) resolve() StackInfo {
+	lf.once.Do(func() {
+		lf.frames = resolveFrames(lf.pcs, lf.opts)
+		lf.pcs = nil
+	})
+	return lf.frames
+}
+
+// selfPackage is the full package path of this package (e.g. "github.com/go-andiamo/stackerr")
+var selfPackage = reflect.TypeOf(err{}).PkgPath()
+
+// isSelfFrame determines whether the frame is in this package (but not in a test file of this package)
+func isSelfFrame(frame runtime.Frame) bool {
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	full, _ := packageFromFunction(frame.Function)
+	return full == selfPackage
+}
+
+// symbol is a resolved (symbolized) program counter
+type symbol struct {
+	frame    runtime.Frame
+	pkg      string
+	shortPkg string
+}
+
+// symbols is the cache of resolved program counters (symbolization of a given program counter never changes)
+var symbols sync.Map
+
+// symbolize resolves the program counter (as returned by runtime.Callers) into a frame - caching the result
+//
+// Note: runtime.Callers returns a program counter for each logical frame (including inlined frames) - so each
+// program counter resolves to exactly one frame
+func symbolize(pc uintptr) symbol {
+	if sym, ok := symbols.Load(pc); ok {
+		return sym.(symbol)
+	}
+	fr, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	sym := symbol{frame: fr}
+	sym.pkg, sym.shortPkg = packageFromFunction(fr.Function)
+	symbols.Store(pc, sym)
+	return sym
+}
+
+// Caller returns the frame skip levels above the caller of Caller - i.e. Caller(0) returns the frame of the function
+// calling Caller, Caller(1) returns the frame of its caller, etc. (a negative skip is treated as zero)
+//
+// returns false if there is no such frame
+func Caller(skip int) (runtime.Frame, bool) {
+	var pcs [1]uintptr
+	// skip runtime.Callers and Caller itself...
+	if runtime.Callers(2+max(skip, 0), pcs[:]) == 0 {
+		return runtime.Frame{}, false
+	}
+	return symbolize(pcs[0]).frame, true
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID parses the current goroutine id from the runtime.Stack header (e.g. "goroutine 123 [running]:")
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// String implements fmt.Stringer - rendering the frames using DefaultFrameFormatter (as the stack portion of formatting
+// a StackError with %+v), e.g.
+//
+//	Stack:
+//		main.doSomething:10
+//		main.main:20
+//
+// If DefaultFrameFormatter is nil, the built-in default formatter is used. Leading and trailing whitespace is trimmed
+func (si StackInfo) String() string {
+	f := DefaultFrameFormatterValue()
+	if f == nil {
+		f = &frameFormatter{}
+	}
+	return strings.TrimSpace(FormatStack(si, f))
+}
+
+// TopFunction returns the function name of the innermost (first) frame - or empty string if the stack info is empty
+func (si StackInfo) TopFunction() string {
+	if len(si) > 0 {
+		return si[0].Function
+	}
+	return ""
+}
+
+// Contains determines whether any frame's function name contains the specified substring
+//
+// This is useful in tests - e.g. asserting that an error was created within a particular function (without pinning line numbers)
+func (si StackInfo) Contains(funcSubstring string) bool {
+	for _, fr := range si {
+		if strings.Contains(fr.Function, funcSubstring) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByPackage returns a new StackInfo with only the frames whose (full) package path is the specified package
+//
+// The stack info itself is not modified
+func (si StackInfo) FilterByPackage(pkg string) StackInfo {
+	return si.filter(func(full string) bool {
+		return full == pkg
+	})
+}
+
+// FilterByPrefix returns a new StackInfo with only the frames whose (full) package path starts with the specified prefix
+//
+// The stack info itself is not modified
+func (si StackInfo) FilterByPrefix(prefix string) StackInfo {
+	return si.filter(func(full string) bool {
+		return strings.HasPrefix(full, prefix)
+	})
+}
+
+func (si StackInfo) filter(include func(full string) bool) StackInfo {
+	result := make(StackInfo, 0, len(si))
+	for _, fr := range si {
+		if full, _ := packageFromFunction(fr.Function); include(full) {
+			result = append(result, fr)
+		}
+	}
+	return result
+}
+
+// Frame is a minimal, stable representation of a stack frame (see StackInfo.StableFrames)
+//
+// Unlike runtime.Frame, the fields of Frame do not vary across Go versions
+type Frame struct {
+	Function string
+	Package  string
+	File     string
+	Line     int
+}
+
+// StableFrames returns the frames mapped to Frame - with the Package being the full package path of the frame's function
+func (si StackInfo) StableFrames() []Frame {
+	result := make([]Frame, 0, len(si))
+	for _, fr := range si {
+		pkg, _ := packageFromFunction(fr.Function)
+		result = append(result, Frame{
+			Function: fr.Function,
+			Package:  pkg,
+			File:     fr.File,
+			Line:     fr.Line,
+		})
+	}
+	return result
+}
+
+// CollapsedFrame is a stack frame with a count of how many times it was consecutively repeated (see StackInfo.Collapsed)
+type CollapsedFrame struct {
+	runtime.Frame
+	Repeats int
+}
+
+// Collapsed returns the frames with consecutive identical frames (same function and file - e.g. from recursion)
+// collapsed into a single frame with a repeat count
+//
+// The stack info itself is not modified
+func (si StackInfo) Collapsed() []CollapsedFrame {
+	return si.collapsed(nil)
+}
+
+// collapsed returns the collapsed frames (see Collapsed) - frames are never collapsed across the boundaries
+// of appended stacks (see StackError.AppendStack)
+func (si StackInfo) collapsed(boundaries []int) []CollapsedFrame {
+	result := make([]CollapsedFrame, 0, len(si))
+	for i, fr := range si {
+		if l := len(result) - 1; l >= 0 && result[l].Function == fr.Function && result[l].File == fr.File && !slices.Contains(boundaries, i) {
+			result[l].Repeats++
+		} else {
+			result = append(result, CollapsedFrame{Frame: fr, Repeats: 1})
+		}
+	}
+	return result
+}
+
+// FrameFile returns the file path of the frame - trimmed according to TrimToModule and TrimFilePrefix
+//
+// This is used when outputting file paths (e.g. MarshalJSON) and can be used by custom FrameFormatter implementations
+func FrameFile(frame runtime.Frame) string {
+	if TrimToModule {
+		pkg, _ := packageFromFunction(frame.Function)
+		if pkg == "main" {
+			pkg = mainPackagePath()
+		}
+		if mod := moduleOfPackage(pkg); mod != "" {
+			return path.Join(strings.TrimPrefix(pkg[len(mod):], "/"), path.Base(frame.File))
+		}
+	}
+	if TrimFilePrefix != "" {
+		return strings.TrimPrefix(frame.File, TrimFilePrefix)
+	}
+	return frame.File
+}
+
+var modulePaths = sync.OnceValue(func() (result []string) {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		result = make([]string, 0, len(bi.Deps)+1)
+		if bi.Main.Path != "" {
+			result = append(result, bi.Main.Path)
+		}
+		for _, dep := range bi.Deps {
+			result = append(result, dep.Path)
+		}
+	}
+	return result
+})
+
+// mainPackagePath returns the package path of the main package (functions in which are named "main.X", without the path)
+//
+// If the main package path is not within the main module (e.g. "command-line-arguments" when using go run with files),
+// the main module path is used
+var mainPackagePath = sync.OnceValue(func() string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if bi.Main.Path != "" && (bi.Path == bi.Main.Path || strings.HasPrefix(bi.Path, bi.Main.Path+"/")) {
+			return bi.Path
+		}
+		return bi.Main.Path
+	}
+	return ""
+})
+
+// moduleOfPackage returns the (longest) module path, of the main module or its dependencies, that contains the package
+func moduleOfPackage(pkg string) (result string) {
+	for _, mod := range modulePaths() {
+		if len(mod) > len(result) && (pkg == mod || strings.HasPrefix(pkg, mod+"/")) {
+			result = mod
+		}
+	}
+	return result
+}
+
+// FormatStack returns the stack info formatted using the FrameFormatter (as the stack portion of formatting a StackError with %+v)
+//
+// This can be used to render a stack independently of an error - e.g. for logging a "current location". If the FrameFormatter
+// is nil, DefaultFrameFormatter is used (and if that is also nil, an empty string is returned)
+func FormatStack(si StackInfo, f FrameFormatter) string {
+	if f == nil {
+		f = DefaultFrameFormatterValue()
+	}
+	return buildString(func(w stringBuffer) {
+		writeFrames(w, si, f, nil, false, nil)
+	})
+}
@@ -0,0 +1,1008 @@
+package stackerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLazyStack(t *testing.T) {
+	create := func(lazy bool) StackError {
+		LazyStack = lazy
+		defer func() {
+			LazyStack = false
+		}()
+		return New("fooey")
+	}
+	errs := make([]StackError, 0, 2)
+	for _, lazy := range []bool{false, true} {
+		errs = append(errs, create(lazy))
+	}
+	eager, lazy := errs[0], errs[1]
+	require.Nil(t, eager.(*err).stack.lazy)
+	require.NotNil(t, lazy.(*err).stack.lazy)
+	require.Nil(t, lazy.(*err).stack.lazy.frames)
+
+	esi := eager.StackInfo()
+	lsi := lazy.StackInfo()
+	require.NotEmpty(t, lsi)
+	require.Equal(t, len(esi), len(lsi))
+	for i := range esi {
+		require.Equal(t, esi[i].Function, lsi[i].Function)
+		require.Equal(t, esi[i].File, lsi[i].File)
+		require.Equal(t, esi[i].Line, lsi[i].Line)
+	}
+	require.Nil(t, lazy.(*err).stack.lazy.pcs)
+	// resolved result is cached...
+	lsi2 := lazy.StackInfo()
+	require.Equal(t, &lsi[0], &lsi2[0])
+	// and shared by derived errors...
+	lsi3 := lazy.WithCause(nil).StackInfo()
+	require.Equal(t, &lsi[0], &lsi3[0])
+}
+
+func BenchmarkNew_Eager(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = New("fooey")
+	}
+}
+
+func BenchmarkNew_Lazy(b *testing.B) {
+	LazyStack = true
+	defer func() {
+		LazyStack = false
+	}()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = New("fooey")
+	}
+}
+
+func TestCaptureStack(t *testing.T) {
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	e := New("fooey").WithCause(errors.New("cause"))
+	require.NotNil(t, e.StackInfo())
+	require.Empty(t, e.StackInfo())
+	require.Equal(t, "fooey: cause", fmt.Sprintf("%+v", e))
+	require.Empty(t, Wrap(e, "wrapped").StackInfo())
+}
+
+func BenchmarkNew_NoCapture(b *testing.B) {
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = New("fooey")
+	}
+}
+
+func TestCaptureGoroutineID(t *testing.T) {
+	require.Equal(t, uint64(0), New("fooey").Goroutine())
+
+	CaptureGoroutineID = true
+	defer func() {
+		CaptureGoroutineID = false
+	}()
+	ch := make(chan StackError, 2)
+	for range 2 {
+		go func() {
+			ch <- New("fooey")
+		}()
+	}
+	e1, e2 := <-ch, <-ch
+	require.NotEqual(t, uint64(0), e1.Goroutine())
+	require.NotEqual(t, uint64(0), e2.Goroutine())
+	require.NotEqual(t, e1.Goroutine(), e2.Goroutine())
+	e3 := New("fooey")
+	require.NotEqual(t, e3.Goroutine(), e1.Goroutine())
+	require.Equal(t, e3.Goroutine(), New("fooey").Goroutine())
+	require.Equal(t, e3.Goroutine(), e3.WithCause(nil).Goroutine())
+
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.Equal(t, fmt.Sprintf("fooey\nGoroutine: %d", e3.Goroutine()), fmt.Sprintf("%+v", e3))
+}
+
+func TestStackInfo_Collapsed(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := recurse(12, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(0))
+	})
+	si := e.StackInfo()
+	require.Len(t, si, 15)
+	cfs := si.Collapsed()
+	require.Len(t, si, 15)
+	require.Len(t, cfs, 3)
+	require.True(t, strings.HasPrefix(cfs[0].Function, "github.com/go-andiamo/stackerr.TestStackInfo_Collapsed.func"))
+	require.Equal(t, 1, cfs[0].Repeats)
+	require.Equal(t, "github.com/go-andiamo/stackerr.recurse", cfs[1].Function)
+	require.Equal(t, 13, cfs[1].Repeats)
+	require.Equal(t, si[1], cfs[1].Frame)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestStackInfo_Collapsed", cfs[2].Function)
+	require.Equal(t, 1, cfs[2].Repeats)
+
+	require.Empty(t, StackInfo{}.Collapsed())
+}
+
+func TestCollapseRecursion(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := recurse(12, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(0))
+	})
+	lines := strings.Split(fmt.Sprintf("%+v", e), "\n")
+	require.Len(t, lines, 17)
+
+	CollapseRecursion = true
+	defer func() {
+		CollapseRecursion = false
+	}()
+	lines = strings.Split(fmt.Sprintf("%+v", e), "\n")
+	require.Len(t, lines, 5)
+	require.Equal(t, "fooey", lines[0])
+	require.Equal(t, "Stack:", lines[1])
+	require.Equal(t, "\t"+e.StackInfo()[0].Function+":"+strconv.Itoa(e.StackInfo()[0].Line), lines[2])
+	require.Equal(t, "\tgithub.com/go-andiamo/stackerr.recurse:"+strconv.Itoa(e.StackInfo()[1].Line)+" (x13)", lines[3])
+	require.Len(t, e.StackInfo(), 15)
+}
+
+func TestFrameFile(t *testing.T) {
+	e := New("fooey")
+	fr, _ := e.CallerFrame()
+	dir := fr.File[:strings.LastIndexByte(fr.File, '/')+1]
+	require.Equal(t, fr.File, FrameFile(fr))
+
+	t.Run("TrimFilePrefix", func(t *testing.T) {
+		TrimFilePrefix = dir
+		defer func() {
+			TrimFilePrefix = ""
+		}()
+		require.Equal(t, "stack_test.go", FrameFile(fr))
+		require.Equal(t, "stack_test.go:"+strconv.Itoa(fr.Line), e.Location())
+		data, err := json.Marshal(e)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"file":"stack_test.go"`)
+		require.NotContains(t, string(data), dir)
+		require.Equal(t, "/other/file.go", FrameFile(runtime.Frame{File: "/other/file.go"}))
+	})
+	t.Run("TrimToModule", func(t *testing.T) {
+		TrimToModule = true
+		defer func() {
+			TrimToModule = false
+		}()
+		require.Equal(t, "stack_test.go", FrameFile(fr))
+		require.Equal(t, "require/require.go", FrameFile(runtime.Frame{
+			Function: "github.com/stretchr/testify/require.Equal",
+			File:     "/go/pkg/mod/github.com/stretchr/testify@v1.10.0/require/require.go",
+		}))
+		require.Equal(t, "sub/file.go", FrameFile(runtime.Frame{
+			Function: "github.com/go-andiamo/stackerr/sub.(*T).M",
+			File:     "/src/stackerr/sub/file.go",
+		}))
+		t.Run("main package", func(t *testing.T) {
+			before := mainPackagePath
+			defer func() {
+				mainPackagePath = before
+			}()
+			mainPackagePath = func() string {
+				return "github.com/go-andiamo/stackerr/cmd/tool"
+			}
+			require.Equal(t, "cmd/tool/main.go", FrameFile(runtime.Frame{
+				Function: "main.main",
+				File:     "/tmp/rv/cmd/tool/main.go",
+			}))
+			mainPackagePath = func() string {
+				return "github.com/go-andiamo/stackerr"
+			}
+			require.Equal(t, "main.go", FrameFile(runtime.Frame{
+				Function: "main.(*T).run.func1",
+				File:     "/tmp/rv/main.go",
+			}))
+			mainPackagePath = func() string {
+				return ""
+			}
+			require.Equal(t, "/tmp/rv/main.go", FrameFile(runtime.Frame{
+				Function: "main.main",
+				File:     "/tmp/rv/main.go",
+			}))
+		})
+		stdlib := runtime.Frame{
+			Function: "testing.tRunner",
+			File:     "/usr/local/go/src/testing/testing.go",
+		}
+		require.Equal(t, stdlib.File, FrameFile(stdlib))
+		TrimFilePrefix = "/usr/local/go/src/"
+		defer func() {
+			TrimFilePrefix = ""
+		}()
+		require.Equal(t, "testing/testing.go", FrameFile(stdlib))
+	})
+}
+
+func TestStackInfo_TopFunction(t *testing.T) {
+	si := StackInfo{
+		{Function: "github.com/org/pkg.Inner"},
+		{Function: "github.com/org/pkg.Outer"},
+	}
+	require.Equal(t, "github.com/org/pkg.Inner", si.TopFunction())
+	require.Equal(t, "", StackInfo{}.TopFunction())
+	require.Equal(t, "", StackInfo(nil).TopFunction())
+
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestStackInfo_TopFunction", New("fooey").StackInfo().TopFunction())
+}
+
+func TestStackInfo_Contains(t *testing.T) {
+	si := StackInfo{
+		{Function: "github.com/org/pkg.Inner"},
+		{Function: "github.com/org/pkg.(*T).Outer"},
+	}
+	require.True(t, si.Contains("Inner"))
+	require.True(t, si.Contains("(*T).Outer"))
+	require.True(t, si.Contains("github.com/org/pkg"))
+	require.False(t, si.Contains("Other"))
+	require.False(t, StackInfo{}.Contains("Inner"))
+
+	require.True(t, New("fooey").StackInfo().Contains("TestStackInfo_Contains"))
+}
+
+func TestError_CauseStack(t *testing.T) {
+	line := 0
+	inner := causeAt(New("inner"), &line)
+	_ = New("fooey")
+	e := Wrap(fmt.Errorf("plain: %w", Wrap(inner, "middle")), "outer")
+	require.NotEqual(t, line, e.StackInfo()[0].Line)
+	cs := e.CauseStack()
+	require.NotEmpty(t, cs)
+	require.Equal(t, line, cs[0].Line)
+	require.Equal(t, inner.(StackError).StackInfo(), cs)
+
+	require.Nil(t, New("fooey").CauseStack())
+	require.Nil(t, Wrap(errors.New("plain"), "fooey").CauseStack())
+}
+
+func TestPreserveCauseStack(t *testing.T) {
+	PreserveCauseStack = true
+	defer func() {
+		PreserveCauseStack = false
+	}()
+	line := 0
+	inner := causeAt(New("inner"), &line)
+	e := Wrap(inner, "outer")
+	require.Equal(t, line, e.StackInfo()[0].Line)
+	require.Equal(t, line, e.CauseStack()[0].Line)
+	e = WrapWithOptions(e, "outer", WithMaxDepth(1))
+	require.Equal(t, line, e.StackInfo()[0].Line)
+	require.Equal(t, "outer: outer: inner", e.FullMessage())
+
+	// non StackError cause still captures at wrap...
+	e = Wrap(errors.New("plain"), "outer")
+	require.NotEqual(t, line, e.StackInfo()[0].Line)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestPreserveCauseStack", e.StackInfo()[0].Function)
+}
+
+func TestStackInfo_FilterByPackage(t *testing.T) {
+	e := NewWithOptions("fooey", WithMaxDepth(0))
+	si := e.StackInfo()
+	require.True(t, si.Contains("testing.tRunner"))
+	original := slices.Clone(si)
+
+	filtered := si.FilterByPackage("github.com/go-andiamo/stackerr")
+	require.NotEmpty(t, filtered)
+	require.Less(t, len(filtered), len(si))
+	for _, fr := range filtered {
+		require.True(t, strings.HasPrefix(fr.Function, "github.com/go-andiamo/stackerr."))
+	}
+	require.Equal(t, original, si)
+
+	require.NotEmpty(t, si.FilterByPackage("testing"))
+	require.Empty(t, si.FilterByPackage("github.com/go-andiamo"))
+	require.Empty(t, StackInfo(nil).FilterByPackage("testing"))
+}
+
+func TestStackInfo_FilterByPrefix(t *testing.T) {
+	e := NewWithOptions("fooey", WithMaxDepth(0))
+	si := e.StackInfo()
+	original := slices.Clone(si)
+
+	filtered := si.FilterByPrefix("github.com/go-andiamo")
+	require.NotEmpty(t, filtered)
+	require.Less(t, len(filtered), len(si))
+	for _, fr := range filtered {
+		require.True(t, strings.HasPrefix(fr.Function, "github.com/go-andiamo/stackerr."))
+	}
+	require.Equal(t, original, si)
+	require.Equal(t, si, si.FilterByPrefix(""))
+	require.Empty(t, si.FilterByPrefix("example.com"))
+}
+
+func TestGetStackInfo_EntryDepth(t *testing.T) {
+	line := 0
+	e := helperEntry(lineNo(&line))
+	fr, ok := e.CallerFrame()
+	require.True(t, ok)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestGetStackInfo_EntryDepth", fr.Function)
+	require.Equal(t, line, fr.Line)
+
+	e = NotFound(fmt.Sprintf("%d", lineNo(&line)))
+	fr, _ = e.CallerFrame()
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestGetStackInfo_EntryDepth", fr.Function)
+	require.Equal(t, line, fr.Line)
+}
+
+// helperEntry simulates a public entry point that uses an intermediate helper layer
+func helperEntry(line int) StackError {
+	return helperLayer(strconv.Itoa(line))
+}
+
+func helperLayer(msg string) StackError {
+	return newError(msg, getStackInfo(2, newOptions()), nil)
+}
+
+func TestExcludeSelf(t *testing.T) {
+	require.Equal(t, "github.com/go-andiamo/stackerr", selfPackage)
+	var e StackError
+	_ = SafeGo(func() error {
+		e = New("fooey")
+		return e
+	})
+	require.True(t, e.StackInfo().Contains("stackerr.SafeGo"))
+
+	ExcludeSelf = true
+	defer func() {
+		ExcludeSelf = false
+	}()
+	_ = SafeGo(func() error {
+		e = New("fooey")
+		return e
+	})
+	si := e.StackInfo()
+	require.False(t, si.Contains("stackerr.SafeGo"))
+	require.False(t, si.Contains("stackerr.New"))
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestExcludeSelf.func3", si[0].Function)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestExcludeSelf", si[1].Function)
+}
+
+func TestStackInfo_StableFrames(t *testing.T) {
+	e := New("fooey")
+	fr, _ := e.CallerFrame()
+	frames := e.StableFrames()
+	require.Len(t, frames, len(e.StackInfo()))
+	require.Equal(t, Frame{
+		Function: "github.com/go-andiamo/stackerr.TestStackInfo_StableFrames",
+		Package:  "github.com/go-andiamo/stackerr",
+		File:     fr.File,
+		Line:     fr.Line,
+	}, frames[0])
+	require.Equal(t, "testing", frames[1].Package)
+	require.Equal(t, "testing.tRunner", frames[1].Function)
+
+	si := StackInfo{
+		{Function: "github.com/foo/bar/v2.(*Thing).Method"},
+		{Function: "example.com/foo%2ebar.Func[...]"},
+		{Function: "main.main"},
+	}
+	frames = si.StableFrames()
+	require.Equal(t, "github.com/foo/bar/v2", frames[0].Package)
+	require.Equal(t, "example.com/foo.bar", frames[1].Package)
+	require.Equal(t, "main", frames[2].Package)
+	require.Empty(t, StackInfo{}.StableFrames())
+}
+
+func BenchmarkNew_NarrowFilter(b *testing.B) {
+	DefaultPackageFilter = PrefixFilter("testing")
+	defer func() {
+		DefaultPackageFilter = nil
+	}()
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = recurse(20, func() StackError {
+			return NewWithOptions("fooey", WithMaxDepth(0))
+		})
+	}
+}
+
+func BenchmarkNew_Unfiltered(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = recurse(20, func() StackError {
+			return NewWithOptions("fooey", WithMaxDepth(0))
+		})
+	}
+}
+
+func TestResolveFrames_MatchesCallersFrames(t *testing.T) {
+	check := func(t *testing.T, pcs []uintptr) {
+		expected := make(StackInfo, 0, len(pcs))
+		frames := runtime.CallersFrames(pcs)
+		for more := len(pcs) > 0; more; {
+			var fr runtime.Frame
+			fr, more = frames.Next()
+			expected = append(expected, fr)
+		}
+		// twice - so that the second is resolved from the cache...
+		for range 2 {
+			actual := resolveFrames(pcs, options{})
+			require.Equal(t, len(expected), len(actual))
+			for i := range expected {
+				require.Equal(t, expected[i].Function, actual[i].Function)
+				require.Equal(t, expected[i].File, actual[i].File)
+				require.Equal(t, expected[i].Line, actual[i].Line)
+			}
+		}
+	}
+	t.Run("recursive", func(t *testing.T) {
+		var pcs []uintptr
+		_ = recurse(5, func() StackError {
+			pcs = make([]uintptr, 64)
+			pcs = pcs[:runtime.Callers(1, pcs)]
+			return nil
+		})
+		check(t, pcs)
+	})
+	t.Run("panic", func(t *testing.T) {
+		var pcs []uintptr
+		func() {
+			defer func() {
+				_ = recover()
+				pcs = make([]uintptr, 64)
+				pcs = pcs[:runtime.Callers(1, pcs)]
+			}()
+			var m map[string]int
+			m["boom"]++
+		}()
+		check(t, pcs)
+	})
+	t.Run("sigpanic", func(t *testing.T) {
+		var pcs []uintptr
+		func() {
+			defer func() {
+				_ = recover()
+				pcs = make([]uintptr, 64)
+				pcs = pcs[:runtime.Callers(1, pcs)]
+			}()
+			var p *int
+			*p++
+		}()
+		require.True(t, resolveFrames(pcs, options{}).Contains("runtime.sigpanic"))
+		check(t, pcs)
+	})
+}
+
+func TestResolveFrames_FilteredUnchanged(t *testing.T) {
+	var pcs []uintptr
+	_ = recurse(5, func() StackError {
+		pcs = make([]uintptr, 64)
+		pcs = pcs[:runtime.Callers(1, pcs)]
+		return nil
+	})
+	all := resolveFrames(pcs, options{})
+	expected := make(StackInfo, 0, len(all))
+	for _, fr := range all {
+		if strings.HasPrefix(fr.Function, "testing.") {
+			expected = append(expected, fr)
+		}
+	}
+	require.NotEmpty(t, expected)
+	DefaultPackageFilter = PrefixFilter("testing")
+	defer func() {
+		DefaultPackageFilter = nil
+	}()
+	require.Equal(t, expected, resolveFrames(pcs, options{}))
+}
+
+func TestError_WithSyntheticFrame(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey")
+	fr, _ := e.CallerFrame()
+	e2 := e.WithSyntheticFrame("templates.render", "/templates/page.tmpl", 42)
+	si := e2.StackInfo()
+	require.Len(t, si, len(e.StackInfo())+1)
+	require.Equal(t, "templates.render", si[0].Function)
+	require.Equal(t, "/templates/page.tmpl", si[0].File)
+	require.Equal(t, 42, si[0].Line)
+	require.Equal(t, fr, si[1])
+	require.Len(t, e.StackInfo(), 1)
+	require.Equal(t, "/templates/page.tmpl:42", e2.Location())
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\ttemplates.render:42\n\t%s:%d", fr.Function, fr.Line), fmt.Sprintf("%+v", e2))
+	require.Equal(t, "templates", e2.StableFrames()[0].Package)
+
+	CaptureStack = false
+	defer func() {
+		CaptureStack = true
+	}()
+	e3 := New("fooey").WithSyntheticFrame("templates.render", "/templates/page.tmpl", 42)
+	require.Len(t, e3.StackInfo(), 1)
+}
+
+func TestResolveFrames_FastPath(t *testing.T) {
+	var pcs []uintptr
+	_ = recurse(5, func() StackError {
+		pcs = make([]uintptr, 64)
+		pcs = pcs[:runtime.Callers(1, pcs)]
+		return nil
+	})
+	for _, maxDepth := range []uint{0, 3, 64} {
+		fast := resolveFrames(pcs, options{maxDepth: maxDepth})
+		// a filter that includes everything forces the general path...
+		DefaultPackageFilter = AllFilters()
+		general := resolveFrames(pcs, options{maxDepth: maxDepth})
+		DefaultPackageFilter = nil
+		require.NotEmpty(t, fast)
+		require.Equal(t, general, fast)
+	}
+}
+
+func BenchmarkResolveFrames_FastPath(b *testing.B) {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = resolveFrames(pcs, options{})
+	}
+}
+
+func BenchmarkResolveFrames_GeneralPath(b *testing.B) {
+	DefaultPackageFilter = AllFilters()
+	defer func() {
+		DefaultPackageFilter = nil
+	}()
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = resolveFrames(pcs, options{})
+	}
+}
+
+func TestError_AppendStack(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	here := New("fooey")
+	var there StackError
+	_ = recurse(1, func() StackError {
+		there = New("deferred")
+		return there
+	})
+	e := here.AppendStack(there)
+	si := e.StackInfo()
+	require.Len(t, si, len(here.StackInfo())+len(there.StackInfo()))
+	require.Equal(t, here.StackInfo(), si[:len(here.StackInfo())])
+	require.Equal(t, there.StackInfo(), si[len(here.StackInfo()):])
+	require.Equal(t, "fooey", e.Error())
+	require.Len(t, here.StackInfo(), 1)
+
+	expected := fmt.Sprintf("fooey\nStack:\n\t%s:%d\n\t--- appended stack ---", si[0].Function, si[0].Line)
+	for _, fr := range si[1:] {
+		expected += fmt.Sprintf("\n\t%s:%d", fr.Function, fr.Line)
+	}
+	require.Equal(t, expected, fmt.Sprintf("%+v", e))
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\t%s:%d", si[0].Function, si[0].Line), fmt.Sprintf("%+v", here))
+
+	// non boundary formatter...
+	DefaultFrameFormatter = &CompactFrameFormatter{}
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.NotContains(t, fmt.Sprintf("%+v", e), "appended")
+	DefaultFrameFormatter = &frameFormatter{}
+
+	// boundaries are kept when appending again (and shifted by synthetic frames)...
+	e2 := e.WithSyntheticFrame("synthetic", "synthetic.go", 1).AppendStack(New("other"))
+	require.Equal(t, 2, strings.Count(fmt.Sprintf("%+v", e2), "--- appended stack ---"))
+	lines := strings.Split(fmt.Sprintf("%+v", e2), "\n")
+	require.Equal(t, "\tsynthetic:1", lines[2])
+	require.Equal(t, "\t--- appended stack ---", lines[4])
+	e3 := New("outer").AppendStack(e2)
+	require.Equal(t, 3, strings.Count(fmt.Sprintf("%+v", e3), "--- appended stack ---"))
+
+	require.Equal(t, here.StackInfo(), here.AppendStack(nil).StackInfo())
+
+	// identical frames either side of a boundary are not collapsed...
+	CollapseRecursion = true
+	defer func() {
+		CollapseRecursion = false
+	}()
+	e4 := New("a").AppendStack(New("b"))
+	si = e4.StackInfo()
+	require.Len(t, si, 2)
+	require.Equal(t, si[0].Function, si[1].Function)
+	require.Equal(t, si[0].Line, si[1].Line)
+	require.Equal(t, fmt.Sprintf("a\nStack:\n\t%s:%d\n\t--- appended stack ---\n\t%s:%d", si[0].Function, si[0].Line, si[1].Function, si[1].Line), fmt.Sprintf("%+v", e4))
+	require.Len(t, si.Collapsed(), 1)
+}
+
+func TestCaller(t *testing.T) {
+	fr, ok := Caller(0)
+	_, _, line, _ := runtime.Caller(0)
+	require.True(t, ok)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestCaller", fr.Function)
+	require.Equal(t, line-1, fr.Line)
+	require.True(t, strings.HasSuffix(fr.File, "stack_test.go"))
+
+	fr, ok = Caller(1)
+	require.True(t, ok)
+	require.Equal(t, "testing.tRunner", fr.Function)
+
+	fr, ok = func() (runtime.Frame, bool) {
+		return Caller(1)
+	}()
+	require.True(t, ok)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestCaller", fr.Function)
+
+	_, ok = Caller(1000)
+	require.False(t, ok)
+	fr, ok = Caller(-1)
+	require.True(t, ok)
+	require.Equal(t, "github.com/go-andiamo/stackerr.TestCaller", fr.Function)
+}
+
+func TestFormatStack(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+	}()
+	e := New("fooey")
+	si := e.StackInfo()
+	require.Equal(t, "fooey"+FormatStack(si, nil), fmt.Sprintf("%+v", e))
+	require.Equal(t, fmt.Sprintf("\nStack:\n\t%s:%d", si[0].Function, si[0].Line), FormatStack(si, nil))
+	require.Equal(t, fmt.Sprintf(" [stack: %s(%s:%d)]", si[0].Function, si[0].File, si[0].Line), FormatStack(si, &CompactFrameFormatter{}))
+	require.Equal(t, "", FormatStack(nil, nil))
+
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	require.Equal(t, "", FormatStack(si, nil))
+	require.NotEmpty(t, FormatStack(si, &JSONFrameFormatter{}))
+}
+
+func TestError_Truncated(t *testing.T) {
+	MaxStackDepth = 5
+	defer func() {
+		MaxStackDepth = 16
+	}()
+	e := recurse(10, func() StackError {
+		return New("fooey")
+	})
+	require.True(t, e.Truncated())
+	require.Len(t, e.StackInfo(), 5)
+	out := fmt.Sprintf("%+v", e)
+	require.True(t, strings.HasSuffix(out, "\n\t... (truncated)"))
+	require.Equal(t, 5, strings.Count(out, "stackerr."))
+	require.True(t, e.Clone().Truncated())
+	require.NotContains(t, fmt.Sprintf("%v", e), "truncated")
+
+	shallow := New("fooey")
+	require.False(t, shallow.Truncated())
+	require.NotContains(t, fmt.Sprintf("%+v", shallow), "truncated")
+
+	MaxStackDepth = 0
+	require.False(t, recurse(10, func() StackError {
+		return New("fooey")
+	}).Truncated())
+
+	// lazy...
+	MaxStackDepth = 5
+	LazyStack = true
+	defer func() {
+		LazyStack = false
+	}()
+	e = recurse(10, func() StackError {
+		return New("fooey")
+	})
+	require.True(t, e.Truncated())
+	require.Len(t, e.StackInfo(), 5)
+}
+
+func TestStackOrderOuterFirst(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+		StackOrderOuterFirst = false
+	}()
+	e := recurse(1, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(3))
+	})
+	si := slices.Clone(e.StackInfo())
+	require.Len(t, si, 3)
+	frameLines := func(si StackInfo) []string {
+		result := make([]string, 0, len(si))
+		for _, fr := range si {
+			result = append(result, fmt.Sprintf("\t%s:%d", fr.Function, fr.Line))
+		}
+		return result
+	}
+	innerFirst := append([]string{"fooey", "Stack:"}, append(frameLines(si), "\t... (truncated)")...)
+	require.Equal(t, innerFirst, strings.Split(fmt.Sprintf("%+v", e), "\n"))
+
+	StackOrderOuterFirst = true
+	rsi := slices.Clone(si)
+	slices.Reverse(rsi)
+	outerFirst := append([]string{"fooey", "Stack:", "\t... (truncated)"}, frameLines(rsi)...)
+	require.Equal(t, outerFirst, strings.Split(fmt.Sprintf("%+v", e), "\n"))
+	// stack info is unaffected...
+	require.Equal(t, si, e.StackInfo())
+	require.Equal(t, "\nStack:\n"+strings.Join(frameLines(rsi), "\n"), FormatStack(si, nil))
+
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	obj := struct {
+		Stack []jsonFrame `json:"stack"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &obj))
+	require.Len(t, obj.Stack, 3)
+	require.Equal(t, si[2].Line, obj.Stack[0].Line)
+	require.Equal(t, si[0].Line, obj.Stack[2].Line)
+	require.Equal(t, si, e.StackInfo())
+}
+
+func TestStackOrderOuterFirst_Boundaries(t *testing.T) {
+	defer func() {
+		StackOrderOuterFirst = false
+	}()
+	here := New("here")
+	there := New("there")
+	e := here.AppendStack(there)
+	si := e.StackInfo()
+	lines := strings.Split(fmt.Sprintf("%+v", e), "\n")
+	boundary := slices.Index(lines, "\t--- appended stack ---")
+	require.Greater(t, boundary, 0)
+	StackOrderOuterFirst = true
+	rlines := strings.Split(fmt.Sprintf("%+v", e), "\n")
+	require.Len(t, rlines, len(lines))
+	rboundary := slices.Index(rlines, "\t--- appended stack ---")
+	// the frames either side of the boundary are swapped...
+	require.Equal(t, fmt.Sprintf("\t%s:%d", si[len(here.StackInfo())].Function, si[len(here.StackInfo())].Line), rlines[rboundary-1])
+	require.Equal(t, fmt.Sprintf("\t%s:%d", si[len(here.StackInfo())-1].Function, si[len(here.StackInfo())-1].Line), rlines[rboundary+1])
+	require.Equal(t, lines[boundary-1], rlines[rboundary+1])
+	require.Equal(t, lines[boundary+1], rlines[rboundary-1])
+}
+
+func TestReverseStack(t *testing.T) {
+	si := StackInfo{{Line: 1}, {Line: 2}, {Line: 3}, {Line: 4}, {Line: 5}}
+	rsi, rb := reverseStack(si, []int{2, 4})
+	require.Equal(t, StackInfo{{Line: 5}, {Line: 4}, {Line: 3}, {Line: 2}, {Line: 1}}, rsi)
+	require.Equal(t, []int{1, 3}, rb)
+	require.Equal(t, 1, si[0].Line)
+	_, rb = reverseStack(si, nil)
+	require.Nil(t, rb)
+}
+
+func TestStackInfo_String(t *testing.T) {
+	DefaultPackageName = "stackerr"
+	defer func() {
+		DefaultPackageName = ""
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	si := recurse(2, func() StackError {
+		return NewWithOptions("fooey", WithMaxDepth(3))
+	}).StackInfo()
+	require.Len(t, si, 3)
+	expect := "Stack:"
+	for _, fr := range si {
+		expect += fmt.Sprintf("\n\t%s:%d", fr.Function, fr.Line)
+	}
+	require.Equal(t, expect, si.String())
+	require.Equal(t, expect, fmt.Sprint(si))
+	require.Len(t, strings.Split(si.String(), "\n"), 4)
+	for _, fr := range si {
+		require.Contains(t, si.String(), fr.Function+":"+strconv.Itoa(fr.Line))
+	}
+
+	DefaultFrameFormatter = nil
+	require.Equal(t, expect, si.String())
+	DefaultFrameFormatter = &CompactFrameFormatter{}
+	require.True(t, strings.HasPrefix(si.String(), "[stack: "+si[0].Function))
+	require.Equal(t, "", StackInfo(nil).String())
+}
+
+func TestError_WithoutStack(t *testing.T) {
+	e := New("fooey").WithCode("code")
+	require.NotEmpty(t, e.StackInfo())
+	stripped := e.WithoutStack()
+	require.Empty(t, stripped.StackInfo())
+	require.False(t, stripped.Truncated())
+	require.NotEmpty(t, e.StackInfo())
+	require.Equal(t, "fooey", stripped.Error())
+	require.Equal(t, "code", stripped.Code())
+	require.Equal(t, "fooey", fmt.Sprintf("%+v", stripped))
+	require.Contains(t, fmt.Sprintf("%+v", e), "Stack:")
+
+	appended := New("here").AppendStack(New("there")).WithoutStack()
+	require.Equal(t, "here", fmt.Sprintf("%+v", appended))
+
+	LazyStack = true
+	defer func() {
+		LazyStack = false
+	}()
+	require.Empty(t, New("lazy").WithoutStack().StackInfo())
+}
+
+func TestStripStack(t *testing.T) {
+	sentinel := errors.New("sentinel")
+	inner := New("inner").WithCause(sentinel)
+	joined := Join(New("one"), errors.New("two"))
+	e := Wrap(Wrap(inner, "middle").WithCauses(inner, joined), "outer")
+	stripped := StripStack(e)
+	require.Equal(t, e.Error(), stripped.Error())
+	require.Equal(t, fmt.Sprintf("%v", e), fmt.Sprintf("%v", stripped))
+	require.True(t, errors.Is(stripped, sentinel))
+	out := fmt.Sprintf("%+v", stripped)
+	require.NotContains(t, out, "Stack:")
+	require.NotContains(t, out, "stack_test.go")
+	Walk(stripped, func(err error) bool {
+		if se, ok := err.(StackError); ok {
+			require.Empty(t, se.StackInfo())
+		}
+		return true
+	})
+	require.Nil(t, stripped.(StackError).CauseStack())
+	// originals unaffected...
+	require.NotEmpty(t, e.StackInfo())
+	require.NotEmpty(t, inner.StackInfo())
+	require.Contains(t, fmt.Sprintf("%+v", e), "Stack:")
+
+	require.NoError(t, StripStack(nil))
+	plain := errors.New("plain")
+	require.Equal(t, plain, StripStack(plain))
+	// not walked through non-StackErrors...
+	wrapped := fmt.Errorf("wrapped: %w", inner)
+	require.Equal(t, wrapped, StripStack(wrapped))
+}
+
+func TestFormatFrameFilter(t *testing.T) {
+	defer func() {
+		FormatFrameFilter = nil
+	}()
+	e := New("fooey")
+	si := slices.Clone(e.StackInfo())
+	isStdlib := func(fr runtime.Frame) bool {
+		return strings.HasPrefix(fr.Function, "testing.") || strings.HasPrefix(fr.Function, "runtime.")
+	}
+	require.True(t, slices.ContainsFunc(si, isStdlib))
+	unfiltered := fmt.Sprintf("%+v", e)
+	require.Contains(t, unfiltered, "\n\ttesting.tRunner:")
+
+	FormatFrameFilter = func(fr runtime.Frame) bool {
+		return !isStdlib(fr)
+	}
+	out := fmt.Sprintf("%+v", e)
+	require.NotContains(t, out, "testing.")
+	require.NotContains(t, out, "runtime.")
+	require.Equal(t, fmt.Sprintf("fooey\nStack:\n\t%s:%d", si[0].Function, si[0].Line), out)
+	// stack info retains all frames...
+	require.Equal(t, si, e.StackInfo())
+
+	data, err := json.Marshal(e)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "testing.")
+	obj := struct {
+		Stack []jsonFrame `json:"stack"`
+	}{}
+	require.NoError(t, json.Unmarshal(data, &obj))
+	require.Len(t, obj.Stack, 1)
+	require.Equal(t, si[0].Line, obj.Stack[0].Line)
+
+	require.Equal(t, "\nStack:\n\t"+si[0].Function+":"+strconv.Itoa(si[0].Line), FormatStack(si, nil))
+
+	FormatFrameFilter = func(fr runtime.Frame) bool {
+		return false
+	}
+	require.Equal(t, "fooey", fmt.Sprintf("%+v", e))
+	data, err = json.Marshal(e)
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey"}`, string(data))
+}
+
+func TestFilterStack(t *testing.T) {
+	si := StackInfo{{Line: 1}, {Line: 2}, {Line: 3}, {Line: 4}, {Line: 5}, {Line: 6}}
+	odd := func(fr runtime.Frame) bool {
+		return fr.Line%2 == 1
+	}
+	fsi, fb := filterStack(si, []int{2, 4}, odd)
+	require.Equal(t, StackInfo{{Line: 1}, {Line: 3}, {Line: 5}}, fsi)
+	require.Equal(t, []int{1, 2}, fb)
+	require.Len(t, si, 6)
+
+	// boundaries with no kept frames before, between or after...
+	keep := func(lines ...int) func(fr runtime.Frame) bool {
+		return func(fr runtime.Frame) bool {
+			return slices.Contains(lines, fr.Line)
+		}
+	}
+	fsi, fb = filterStack(si, []int{2, 4}, keep(5, 6))
+	require.Len(t, fsi, 2)
+	require.Nil(t, fb)
+	fsi, fb = filterStack(si, []int{2, 4}, keep(1, 2))
+	require.Len(t, fsi, 2)
+	require.Nil(t, fb)
+	fsi, fb = filterStack(si, []int{2, 4}, keep(1, 5))
+	require.Len(t, fsi, 2)
+	require.Equal(t, []int{1}, fb)
+}
+
+func TestError_ToMap_MatchesMarshalJSON(t *testing.T) {
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+		FormatFrameFilter = nil
+		StackOrderOuterFirst = false
+		MaxCauseDepth = 0
+	}()
+	e := Wrap(Wrap(New("inner").WithTraceID("abc"), "middle"), "outer").WithCategory(CategoryInternal).WithField("foo", "bar")
+	// the map, round-tripped through JSON, is the same as the JSON...
+	same := func() {
+		direct, err := json.Marshal(e)
+		require.NoError(t, err)
+		viaMap, err := json.Marshal(e.ToMap())
+		require.NoError(t, err)
+		require.JSONEq(t, string(direct), string(viaMap))
+	}
+	same()
+
+	DefaultFrameFormatter = nil
+	_, ok := e.ToMap()["stack"]
+	require.False(t, ok)
+	same()
+	DefaultFrameFormatter = &frameFormatter{}
+
+	StackOrderOuterFirst = true
+	si := e.StackInfo()
+	stack := e.ToMap()["stack"].([]map[string]any)
+	require.Equal(t, si[len(si)-1].Line, stack[0]["line"])
+	same()
+	StackOrderOuterFirst = false
+
+	FormatFrameFilter = func(fr runtime.Frame) bool {
+		return fr.Line == si[0].Line
+	}
+	require.Len(t, e.ToMap()["stack"], 1)
+	same()
+	FormatFrameFilter = nil
+
+	MaxCauseDepth = 1
+	cause := e.ToMap()["cause"].(map[string]any)
+	require.Equal(t, "... (1 more)", cause["cause"])
+	same()
+}
+
+func TestError_AppendStack_Truncated(t *testing.T) {
+	shallow := func() StackError {
+		return NewWithOptions("shallow", WithMaxDepth(64))
+	}
+	deep := func() StackError {
+		return recurse(10, func() StackError {
+			return NewWithOptions("deep", WithMaxDepth(3))
+		})
+	}
+	require.False(t, shallow().Truncated())
+	require.True(t, deep().Truncated())
+	require.False(t, shallow().AppendStack(shallow()).Truncated())
+	require.True(t, shallow().AppendStack(deep()).Truncated())
+	require.True(t, deep().AppendStack(shallow()).Truncated())
+	require.True(t, deep().AppendStack(nil).Truncated())
+	require.True(t, strings.HasSuffix(fmt.Sprintf("%+v", deep().AppendStack(shallow())), "\n\t... (truncated)"))
+}
@@ -0,0 +1,15 @@
+package stackerr
+
+import "maps"
+
+func (e *err) WithValue(key, value any) StackError {
+	r := e.clone()
+	r.values = make(map[any]any, len(e.values)+1)
+	maps.Copy(r.values, e.values)
+	r.values[key] = value
+	return r
+}
+
+func (e *err) Value(key any) any {
+	return e.values[key]
+}
@@ -0,0 +1,29 @@
+package stackerr
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type testValueKey struct{}
+
+func TestError_WithValue(t *testing.T) {
+	DefaultFrameFormatter = nil
+	defer func() {
+		DefaultFrameFormatter = &frameFormatter{}
+	}()
+	e := New("fooey")
+	require.Nil(t, e.Value(testValueKey{}))
+	e2 := e.WithValue(testValueKey{}, "bar")
+	require.Equal(t, "bar", e2.Value(testValueKey{}))
+	require.Nil(t, e.Value(testValueKey{}))
+	e3 := e2.WithValue("other", 42)
+	require.Equal(t, "bar", e3.Value(testValueKey{}))
+	require.Equal(t, 42, e3.Value("other"))
+	require.Nil(t, e2.Value("other"))
+	require.Equal(t, "fooey", fmt.Sprintf("%+v", e3))
+	data, err := e3.(*err).MarshalJSON()
+	require.NoError(t, err)
+	require.Equal(t, `{"message":"fooey"}`, string(data))
+}